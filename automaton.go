@@ -0,0 +1,136 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+// automaton holds the failure links that turn the trie into an Aho-Corasick
+// automaton, so that every word in an input can be found in one pass.
+type automaton struct {
+	states map[*node]acState
+}
+
+// acState is what the automaton knows about a node beyond the trie itself.
+type acState struct {
+	// fail is the node for the longest proper suffix of this node's path
+	// that is also a path in the trie
+	fail *node
+	// out is the nearest node along the fail links that ends a word, or nil
+	out *node
+	// depth is the length of the node's path in runes
+	depth int
+}
+
+// BuildAutomaton adds failure links to the trie so that ScanPositions can find
+// every word in its input in a single pass. There is no need to call it, as
+// ScanPositions builds the links when they are missing or out of date, but it
+// allows for paying that cost up front rather than on the first scan. Adding
+// or deleting a word marks the links as out of date.
+func (t *Trie) BuildAutomaton() {
+	if t == nil {
+		return
+	}
+	t.automaton.Store(buildAutomaton(t.root))
+}
+
+// buildAutomaton works out the failure links breadth first, as a node's link
+// depends on the links of the nodes above it.
+func buildAutomaton(root *node) *automaton {
+	a := &automaton{states: map[*node]acState{root: {fail: root}}}
+
+	queue := []*node{root}
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+		st := a.states[n]
+
+		for r, ch := range n.children {
+			fail := root
+			if n != root {
+				f := st.fail
+				for f != root && f.children[r] == nil {
+					f = a.states[f].fail
+				}
+				if next := f.children[r]; next != nil {
+					fail = next
+				}
+			}
+
+			out := a.states[fail].out
+			if fail.isTerminated && fail != root {
+				out = fail
+			}
+
+			a.states[ch] = acState{fail: fail, out: out, depth: st.depth + 1}
+			queue = append(queue, ch)
+		}
+	}
+
+	return a
+}
+
+// ScanPositions reports, for each position in s at which one or more words
+// from the trie end, the byte offset in s just after the last rune of those
+// words along with the words themselves, longest first. Words overlap freely,
+// so "she" and "he" are both reported at the end of "ushers". The words are as
+// they are stored in the trie. For a trie made with WithReverse s is scanned
+// from its end, and the offsets are those where the words start.
+//
+// This is the output of an Aho-Corasick scan, which takes time in proportion
+// to the length of s plus the number of words found, however many words are
+// in the trie. The failure links it relies on are built the first time it is
+// called and kept until a word is added or deleted, so the first call after a
+// change also pays for a pass over the whole trie. Building them is safe with
+// other lookups running at the same time, as long as nothing changes the
+// trie. See BuildAutomaton.
+func (t *Trie) ScanPositions(s string, fn func(pos int, words []string)) {
+	if t == nil {
+		return
+	}
+
+	// two scans at once may both build the links, but either result will do
+	a := t.automaton.Load()
+	if a == nil {
+		a = buildAutomaton(t.root)
+		t.automaton.Store(a)
+	}
+
+	rs, offsets := t.scanRunes(s)
+	now := t.clock()
+
+	n := t.root
+	for i, r := range rs {
+		for n != t.root && n.children[r] == nil {
+			n = a.states[n].fail
+		}
+		if ch := n.children[r]; ch != nil {
+			n = ch
+		}
+
+		var words []string
+		for m := n; m != nil; m = a.states[m].out {
+			if m != t.root && m.live(now) {
+				words = append(words, t.word(rs[i+1-a.states[m].depth:i+1]))
+			}
+		}
+		if len(words) == 0 {
+			continue
+		}
+
+		start, end := span(s, offsets[i:i+1])
+		if t.reverse {
+			fn(start, words)
+		} else {
+			fn(end, words)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestTrieScanPositions(t *testing.T) {
+
+	list := []string{"he", "she", "his", "hers", "héros"}
+
+	cases := []struct {
+		In  string
+		Out map[int][]string
+	}{
+		{"ushers", map[int][]string{4: {"she", "he"}, 6: {"hers"}}},
+		{"USHERS", map[int][]string{4: {"she", "he"}, 6: {"hers"}}},
+		{"hishe", map[int][]string{3: {"his"}, 5: {"she", "he"}}},
+		{"un héros", map[int][]string{9: {"héros"}}},
+		{"nothing", map[int][]string{}},
+		{"", map[int][]string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	scan := func(s string) map[int][]string {
+		got := map[int][]string{}
+		trie.ScanPositions(s, func(pos int, words []string) {
+			got[pos] = words
+		})
+		return got
+	}
+
+	for _, c := range cases {
+		if got := scan(c.In); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %q Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+	trie.BuildAutomaton()
+	for _, c := range cases {
+		if got := scan(c.In); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %q after BuildAutomaton Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+	rev := New(WithReverse())
+	if err := rev.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	got := map[int][]string{}
+	rev.ScanPositions("ushers", func(pos int, words []string) {
+		got[pos] = words
+	})
+	if want := map[int][]string{1: {"she"}, 2: {"hers", "he"}}; !reflect.DeepEqual(want, got) {
+		t.Errorf("For reverse Expected %v, got %v", want, got)
+	}
+
+}
+
+func TestTrieScanPositionsMatches(t *testing.T) {
+
+	list := []string{"a", "ab", "bab", "bc", "bca", "c", "caa", "abcab", "cab"}
+	in := "abccabcaabcabbca"
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	trie.BuildAutomaton()
+
+	want := []string{}
+	for m := range trie.Matches(in, 0) {
+		want = append(want, fmt.Sprintf("%s@%d", m.Word, m.Offset+len(m.Word)))
+	}
+
+	got := []string{}
+	trie.ScanPositions(in, func(pos int, words []string) {
+		for _, w := range words {
+			got = append(got, fmt.Sprintf("%s@%d", w, pos))
+		}
+	})
+
+	sort.Strings(want)
+	sort.Strings(got)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+}
+
+func TestTrieScanPositionsLazy(t *testing.T) {
+
+	trie := New()
+
+	if err := trie.Load([]string{"he", "she", "hers"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	scan := func() map[int][]string {
+		got := map[int][]string{}
+		trie.ScanPositions("ushers", func(pos int, words []string) {
+			got[pos] = words
+		})
+		return got
+	}
+
+	if trie.automaton.Load() != nil {
+		t.Errorf("Expected no automaton before the first scan")
+	}
+	if want := map[int][]string{4: {"she", "he"}, 6: {"hers"}}; !reflect.DeepEqual(want, scan()) {
+		t.Errorf("Expected %v, got %v", want, scan())
+	}
+
+	built := trie.automaton.Load()
+	scan()
+	if err := trie.Add("she"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if trie.automaton.Load() != built {
+		t.Errorf("Expected the automaton to be kept between scans")
+	}
+
+	if err := trie.Add("us"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if trie.automaton.Load() != nil {
+		t.Errorf("Expected Add to mark the automaton out of date")
+	}
+	if want := map[int][]string{2: {"us"}, 4: {"she", "he"}, 6: {"hers"}}; !reflect.DeepEqual(want, scan()) {
+		t.Errorf("After Add Expected %v, got %v", want, scan())
+	}
+
+	if err := trie.Delete("he"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if want := map[int][]string{2: {"us"}, 4: {"she"}, 6: {"hers"}}; !reflect.DeepEqual(want, scan()) {
+		t.Errorf("After Delete Expected %v, got %v", want, scan())
+	}
+
+	trie.DeleteSet(map[string]struct{}{"she": {}})
+	if want := map[int][]string{2: {"us"}, 6: {"hers"}}; !reflect.DeepEqual(want, scan()) {
+		t.Errorf("After DeleteSet Expected %v, got %v", want, scan())
+	}
+
+}
+
+// Run with -race: the failure links are built by whichever scan gets there
+// first, while others may be scanning too.
+func TestTrieScanPositionsConcurrent(t *testing.T) {
+
+	trie := New()
+
+	if err := trie.Load([]string{"he", "she", "hers"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	want := map[int][]string{4: {"she", "he"}, 6: {"hers"}}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				got := map[int][]string{}
+				trie.ScanPositions("ushers", func(pos int, words []string) {
+					got[pos] = words
+				})
+				if !reflect.DeepEqual(want, got) {
+					t.Errorf("Expected %v, got %v", want, got)
+				}
+				if got, _ := trie.IsContained("ushers", 0); !got {
+					t.Errorf("For %s Expected %t, got %t", "ushers", true, got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+// ByteTrie is a trie keyed on raw bytes rather than runes. Keys aren't
+// lowercased or decoded as UTF-8, so it works for binary data such as hashes
+// that the rune based Trie would mangle.
+type ByteTrie struct {
+	root  *byteNode
+	count int
+}
+
+// NewByteTrie returns a new initialized byte trie
+func NewByteTrie() *ByteTrie {
+	return &ByteTrie{newByteNode(), 0}
+}
+
+// AddBytes adds a key to the trie creating any new nodes it needs
+func (t *ByteTrie) AddBytes(b []byte) {
+	n := t.root
+	for _, v := range b {
+		ch, ok := n.children[v]
+		if !ok {
+			ch = newByteNode()
+			n.children[v] = ch
+		}
+		n = ch
+	}
+
+	if !n.isTerminated {
+		n.isTerminated = true
+		t.count++
+	}
+}
+
+// FindBytes determines if the input exactly matches a key in the trie
+func (t *ByteTrie) FindBytes(b []byte) bool {
+	n := t.root.walk(b)
+	return n != nil && n.isTerminated
+}
+
+// HasPrefixBytes determines if there is a key in the trie that starts with
+// the input.
+func (t *ByteTrie) HasPrefixBytes(b []byte) bool {
+	n := t.root.walk(b)
+	return n != nil && (n.isTerminated || len(n.children) > 0)
+}
+
+// Count returns the number of keys in the trie
+func (t *ByteTrie) Count() int {
+	return t.count
+}
+
+type byteNode struct {
+	children     map[byte]*byteNode
+	isTerminated bool
+}
+
+func newByteNode() *byteNode {
+	return &byteNode{make(map[byte]*byteNode), false}
+}
+
+func (n *byteNode) walk(value []byte) *byteNode {
+	for _, v := range value {
+		ch, ok := n.children[v]
+		if !ok {
+			return nil
+		}
+		n = ch
+	}
+	return n
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import "testing"
+
+func TestByteTrie(t *testing.T) {
+
+	list := [][]byte{
+		{0xff, 0xfe, 0x00},
+		{0xff, 0xfe, 0x00, 0x01},
+		{0xc3, 0x28},
+		[]byte("Copy"),
+		{0xff, 0xfe, 0x00},
+	}
+
+	cases := []struct {
+		In     []byte
+		Out    bool
+		Prefix bool
+	}{
+		{[]byte{0xff, 0xfe, 0x00}, true, true},
+		{[]byte{0xff, 0xfe, 0x00, 0x01}, true, true},
+		{[]byte{0xff, 0xfe}, false, true},
+		{[]byte{0xff, 0xfd}, false, false},
+		{[]byte{0xc3, 0x28}, true, true},
+		{[]byte{0xef, 0xbf, 0xbd}, false, false},
+		{[]byte("Copy"), true, true},
+		{[]byte("copy"), false, false},
+		{[]byte{}, false, true},
+	}
+
+	trie := NewByteTrie()
+
+	for _, v := range list {
+		trie.AddBytes(v)
+	}
+
+	if trie.Count() != 4 {
+		t.Errorf("Expected %d, got %d", 4, trie.Count())
+	}
+
+	for _, c := range cases {
+		if got := trie.FindBytes(c.In); c.Out != got {
+			t.Errorf("For %x Expected %t, got %t", c.In, c.Out, got)
+		}
+		if got := trie.HasPrefixBytes(c.In); c.Prefix != got {
+			t.Errorf("For %x prefix Expected %t, got %t", c.In, c.Prefix, got)
+		}
+	}
+
+}
@@ -0,0 +1,184 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsorted is returned by BuildDAFSA when its input isn't in sorted order.
+var ErrUnsorted = errors.New("words are not in sorted order")
+
+// DAFSA is an immutable set of words stored as a minimal deterministic acyclic
+// finite-state automaton. Words that end the same way share their suffixes as
+// well as their prefixes, so a large static dictionary takes far less memory
+// than in a Trie. Words are lowercased, as they are in the Trie.
+type DAFSA struct {
+	root   *dafsaState
+	count  int
+	states int
+}
+
+type dafsaState struct {
+	id    int
+	final bool
+	edges []dafsaEdge
+}
+
+type dafsaEdge struct {
+	label rune
+	to    *dafsaState
+}
+
+// dafsaBuilder holds the state of the incremental construction: states that
+// haven't been checked against the register yet, and the register of
+// already minimized states keyed by their signature.
+type dafsaBuilder struct {
+	next      int
+	unchecked []*dafsaState
+	register  map[string]*dafsaState
+}
+
+// BuildDAFSA builds a DAFSA from a list of words. The words must be sorted
+// once lowercased, which lets the automaton be minimized as it is built
+// rather than afterwards. Duplicates are ignored.
+func BuildDAFSA(sortedWords []string) (*DAFSA, error) {
+	b := &dafsaBuilder{register: make(map[string]*dafsaState)}
+	d := &DAFSA{root: b.newState()}
+	b.unchecked = append(b.unchecked, d.root)
+
+	var prev []rune
+	for i, w := range sortedWords {
+		rs := []rune(strings.ToLower(w))
+		if i > 0 {
+			switch c := compareRunes(prev, rs); {
+			case c == 0:
+				continue
+			case c > 0:
+				return nil, ErrUnsorted
+			}
+		}
+
+		common := 0
+		for common < len(prev) && common < len(rs) && prev[common] == rs[common] {
+			common++
+		}
+
+		b.minimize(common)
+
+		s := b.unchecked[common]
+		for _, r := range rs[common:] {
+			ch := b.newState()
+			s.edges = append(s.edges, dafsaEdge{r, ch})
+			b.unchecked = append(b.unchecked, ch)
+			s = ch
+		}
+		s.final = true
+
+		d.count++
+		prev = rs
+	}
+
+	b.minimize(0)
+	d.states = len(b.register) + 1
+
+	return d, nil
+}
+
+func (b *dafsaBuilder) newState() *dafsaState {
+	b.next++
+	return &dafsaState{id: b.next}
+}
+
+// minimize replaces the unchecked states deeper than depth with an equivalent
+// state from the register, or registers them if there is none yet. Each
+// unchecked state is always the last edge of the one before it.
+func (b *dafsaBuilder) minimize(depth int) {
+	for i := len(b.unchecked) - 1; i > depth; i-- {
+		s := b.unchecked[i]
+		parent := b.unchecked[i-1]
+
+		key := s.signature()
+		if r, ok := b.register[key]; ok {
+			parent.edges[len(parent.edges)-1].to = r
+		} else {
+			b.register[key] = s
+		}
+	}
+	b.unchecked = b.unchecked[:depth+1]
+}
+
+// signature identifies a state by whether it is final and where its edges
+// lead. Two states with the same signature accept the same suffixes.
+func (s *dafsaState) signature() string {
+	var sb strings.Builder
+	if s.final {
+		sb.WriteByte('1')
+	} else {
+		sb.WriteByte('0')
+	}
+	for _, e := range s.edges {
+		sb.WriteByte(' ')
+		sb.WriteString(strconv.Itoa(int(e.label)))
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(e.to.id))
+	}
+	return sb.String()
+}
+
+func (s *dafsaState) child(r rune) *dafsaState {
+	i := sort.Search(len(s.edges), func(i int) bool { return s.edges[i].label >= r })
+	if i < len(s.edges) && s.edges[i].label == r {
+		return s.edges[i].to
+	}
+	return nil
+}
+
+func compareRunes(a, b []rune) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// Find determines if the input exactly matches a word in the DAFSA
+func (d *DAFSA) Find(s string) bool {
+	n := d.root
+	for _, r := range strings.ToLower(s) {
+		if n = n.child(r); n == nil {
+			return false
+		}
+	}
+	return n.final
+}
+
+// Count returns the number of words in the DAFSA
+func (d *DAFSA) Count() int {
+	return d.count
+}
+
+// States returns the number of states in the automaton, which is what
+// determines its size in memory.
+func (d *DAFSA) States() int {
+	return d.states
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestBuildDAFSA(t *testing.T) {
+
+	list := []string{"Tap", "tap", "taps", "top", "tops", "zap", "zaps"}
+
+	cases := []struct {
+		In  string
+		Out bool
+	}{
+		{"tap", true},
+		{"TAPS", true},
+		{"top", true},
+		{"tops", true},
+		{"zaps", true},
+		{"ta", false},
+		{"zop", false},
+		{"tapss", false},
+		{"", false},
+	}
+
+	d, err := BuildDAFSA(list)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if d.Count() != 6 {
+		t.Errorf("Expected %d, got %d", 6, d.Count())
+	}
+
+	// root, t, z, the shared a|o state, p and s: every word ends in p or ps.
+	if d.States() != 6 {
+		t.Errorf("Expected %d states, got %d", 6, d.States())
+	}
+
+	for _, c := range cases {
+		if got := d.Find(c.In); c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestBuildDAFSAUnsorted(t *testing.T) {
+	_, err := BuildDAFSA([]string{"tap", "rap"})
+	if !errors.Is(err, ErrUnsorted) {
+		t.Errorf("Expected %s, got %v", ErrUnsorted, err)
+	}
+}
+
+func TestBuildDAFSAFull(t *testing.T) {
+	data, err := fileToStringSlice("dict.full.json")
+	if err != nil {
+		t.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	d, err := BuildDAFSA(data)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if d.Count() != len(data) {
+		t.Errorf("Expected %d, got %d", len(data), d.Count())
+	}
+
+	for _, w := range data {
+		if !d.Find(w) {
+			t.Errorf("For %s Expected %t, got %t", w, true, false)
+		}
+	}
+}
+
+// BenchmarkDAFSAMemory reports the heap retained by a Trie and by a DAFSA
+// holding all of dict.full.json.
+func BenchmarkDAFSAMemory(b *testing.B) {
+	data, err := fileToStringSlice("dict.full.json")
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	var trieBytes, dafsaBytes uint64
+	for n := 0; n < b.N; n++ {
+		before := heapInUse()
+		trie := New()
+		if err := trie.Load(data); err != nil {
+			b.Fatalf("Expected no error, got %s", err)
+		}
+		trieBytes += heapInUse() - before
+		runtime.KeepAlive(trie)
+
+		before = heapInUse()
+		d, err := BuildDAFSA(data)
+		if err != nil {
+			b.Fatalf("Expected no error, got %s", err)
+		}
+		dafsaBytes += heapInUse() - before
+		runtime.KeepAlive(d)
+	}
+
+	b.ReportMetric(float64(trieBytes)/float64(b.N), "trie-B")
+	b.ReportMetric(float64(dafsaBytes)/float64(b.N), "dafsa-B")
+}
+
+func heapInUse() uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import "time"
+
+// WithClock replaces time.Now as the clock used to decide when words added
+// with AddWithExpiry have expired. It is mostly useful for tests.
+func WithClock(now func() time.Time) Option {
+	return func(t *Trie) {
+		t.now = now
+	}
+}
+
+// AddWithExpiry performs Add, and makes the word act as if it were absent
+// from the expires time on when looking for words in an input: Find and its
+// variants, IsContained and its variants, HasSuffix, ShortestPrefix,
+// IsExtensionOf, AnyPrefixMatches, FindAll, Matches, ReplaceFunc,
+// SplitLongest and ScanPositions. Expired words still count towards Count and
+// Total, and are still listed by methods that enumerate words, until
+// PurgeExpired takes them out. Adding the word again with Add makes it
+// permanent. Expiry times are written to the journal given to WithJournal and
+// restored by Replay, but aren't kept by MarshalJSON or Encode.
+func (t *Trie) AddWithExpiry(s string, expires time.Time) error {
+	_, _, err := t.add(s, expires.UnixNano())
+	return err
+}
+
+// PurgeExpired removes every word whose expiry time has passed and returns
+// how many were removed.
+func (t *Trie) PurgeExpired() int {
+	if t == nil || !t.expiring {
+		return 0
+	}
+
+	now := t.clock()
+	return t.removeWhere(func(n *node, _ []rune) bool {
+		return !n.live(now)
+	})
+}
+
+// clock returns the current time in Unix nanoseconds for checking expiry, or
+// 0 without reading the clock if no word has ever been given an expiry.
+func (t *Trie) clock() int64 {
+	if !t.expiring {
+		return 0
+	}
+	if t.now == nil {
+		return time.Now().UnixNano()
+	}
+	return t.now().UnixNano()
+}
+
+// live reports whether n is the end of a word that hasn't expired by now
+func (n *node) live(now int64) bool {
+	return n.isTerminated && (n.expires == 0 || now < n.expires)
+}
@@ -0,0 +1,182 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrieAddWithExpiry(t *testing.T) {
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+
+	trie := New(WithClock(func() time.Time { return now }))
+
+	if err := trie.Load([]string{"copy", "work"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.AddWithExpiry("spam", start.Add(time.Minute)); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.AddWithExpiry("scam", start.Add(time.Hour)); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.AddWithExpiry("work", start.Add(time.Minute)); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Add("scam"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	cases := []struct {
+		After     time.Duration
+		In        string
+		Find      bool
+		Contained bool
+	}{
+		{0, "spam", true, true},
+		{0, "work", true, true},
+		{30 * time.Second, "spam", true, true},
+		{time.Minute, "spam", false, false},
+		{time.Minute, "work", false, false},
+		{time.Minute, "copy", true, true},
+		{2 * time.Hour, "scam", true, true},
+		{2 * time.Hour, "spammer", false, false},
+	}
+
+	for _, c := range cases {
+		now = start.Add(c.After)
+
+		if got := trie.Find(c.In); c.Find != got {
+			t.Errorf("For %s after %s Expected %t, got %t", c.In, c.After, c.Find, got)
+		}
+		if got, _ := trie.IsContained(c.In, 0); c.Contained != got {
+			t.Errorf("For %s after %s Expected contained %t, got %t", c.In, c.After, c.Contained, got)
+		}
+	}
+
+	if trie.Count() != 4 {
+		t.Errorf("Expected %d, got %d", 4, trie.Count())
+	}
+
+	if got := trie.WordsOfLength(4); !reflect.DeepEqual([]string{"copy", "scam", "spam", "work"}, got) {
+		t.Errorf("Expected expired words to be listed, got %v", got)
+	}
+
+	if got := trie.PurgeExpired(); got != 2 {
+		t.Errorf("Expected %d purged, got %d", 2, got)
+	}
+
+	if got := trie.SortedWords(); !reflect.DeepEqual([]string{"copy", "scam"}, got) {
+		t.Errorf("Expected %v, got %v", []string{"copy", "scam"}, got)
+	}
+
+	if err := trie.validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if got := trie.PurgeExpired(); got != 0 {
+		t.Errorf("Expected %d purged, got %d", 0, got)
+	}
+
+}
+
+func TestTrieExpiredLookups(t *testing.T) {
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	clock := WithClock(func() time.Time { return now })
+
+	trie := New(clock)
+	rev := New(clock, WithReverse())
+
+	for _, tr := range []*Trie{trie, rev} {
+		if err := tr.AddWithExpiry("co.uk", start.Add(time.Minute)); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+	}
+
+	cases := []struct {
+		Name   string
+		Lookup func() bool
+	}{
+		{"HasSuffix reverse", func() bool { return rev.HasSuffix("bbc.co.uk") }},
+		{"HasSuffix", func() bool { return trie.HasSuffix("bbc.co.uk") }},
+		{"IsContainedWholeWord", func() bool {
+			got, _ := trie.IsContainedWholeWord("x co.uk y", 0)
+			return got
+		}},
+		{"IsContainedMinBytes", func() bool {
+			got, _ := trie.IsContainedMinBytes("x co.uk y", 0)
+			return got
+		}},
+		{"ShortestPrefix", func() bool {
+			_, got := trie.ShortestPrefix("co.uk/news")
+			return got
+		}},
+		{"IsExtensionOf", func() bool {
+			got, _ := trie.IsExtensionOf("co.uk/news")
+			return got
+		}},
+		{"AnyPrefixMatches", func() bool { return trie.AnyPrefixMatches([]string{"co.uk/news"})[0] }},
+		{"FindAll", func() bool { return len(trie.FindAll("x co.uk y", 0, false)) > 0 }},
+		{"Matches", func() bool {
+			for range trie.Matches("x co.uk y", 0) {
+				return true
+			}
+			return false
+		}},
+		{"ReplaceFunc", func() bool {
+			return trie.ReplaceFunc("x co.uk y", func(string) string { return "*" }) != "x co.uk y"
+		}},
+		{"SplitLongest", func() bool {
+			_, _, got := trie.SplitLongest("co.uk/news")
+			return got
+		}},
+	}
+
+	for _, c := range cases {
+		now = start
+		if !c.Lookup() {
+			t.Errorf("For %s Expected %t before expiry, got %t", c.Name, true, false)
+		}
+
+		now = start.Add(time.Minute)
+		if c.Lookup() {
+			t.Errorf("For %s Expected %t after expiry, got %t", c.Name, false, true)
+		}
+	}
+
+}
+
+func TestTriePurgeExpiredNone(t *testing.T) {
+	trie := New()
+
+	if err := trie.Load([]string{"copy", "work"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if got := trie.PurgeExpired(); got != 0 {
+		t.Errorf("Expected %d purged, got %d", 0, got)
+	}
+
+	var nilTrie *Trie
+	if got := nilTrie.PurgeExpired(); got != 0 {
+		t.Errorf("Expected %d purged, got %d", 0, got)
+	}
+}
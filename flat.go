@@ -0,0 +1,260 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBadEncoding is returned by DecodeTrie when its input isn't a valid flat
+// encoding of a trie.
+var ErrBadEncoding = errors.New("invalid flat trie encoding")
+
+// The flat encoding is a header followed by one fixed size record per node.
+// Nodes are laid out breadth first, so the children of a node are always
+// next to each other, sorted by rune, and after their parent. The root is
+// record 0.
+//
+// Header:
+//
+//	[0] flatMagic
+//	[1] flatVersion
+//	[2] flags: bit 0 WithReverse, bit 1 WithTrimSpace
+//	[3] number of node records
+//
+// Node record:
+//
+//	[0] rune
+//	[1] bit 0 terminal, remaining bits the number of children
+//	[2] index of the first child record
+//	[3] occurrences
+const (
+	flatMagic   = 0x54524945 // "TRIE"
+	flatVersion = 1
+
+	flatHeaderLen = 4
+	flatRecordLen = 4
+
+	flatReverse   = 1 << 0
+	flatTrimSpace = 1 << 1
+
+	flatTerminal = 1 << 0
+)
+
+// Encode returns the trie as a flat array of nodes. It can be turned back into
+// a trie with DecodeTrie, or searched where it is with NewFlatTrie, such as
+// from a file mapped into memory. Branches that don't lead to a word are left
+// out. Of the options, only WithReverse and WithTrimSpace are part of the
+// encoding: WithFolder, WithRuneEquivalence, WithSeparator and
+// WithValidateUTF8 are lost, so a decoded trie may not find words that the
+// original matched through them.
+func (t *Trie) Encode() []uint32 {
+	if t == nil {
+		return New().Encode()
+	}
+
+	var flags uint32
+	if t.reverse {
+		flags |= flatReverse
+	}
+	if t.trimSpace {
+		flags |= flatTrimSpace
+	}
+
+	data := []uint32{flatMagic, flatVersion, flags, 0}
+
+	queue := []*node{t.root}
+	next := uint32(1)
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+
+		var bits uint32
+		if n.isTerminated {
+			bits |= flatTerminal
+		}
+		keys := []rune{}
+		for _, k := range n.sortedKeys() {
+			if n.children[k].hasWords() {
+				keys = append(keys, k)
+			}
+		}
+		bits |= uint32(len(keys)) << 1
+
+		data = append(data, uint32(n.value), bits, next, uint32(n.occurrences))
+
+		for _, k := range keys {
+			queue = append(queue, n.children[k])
+		}
+		next += uint32(len(keys))
+	}
+	data[3] = uint32(len(queue))
+
+	return data
+}
+
+// DecodeTrie rebuilds a trie from the output of Encode. The flat records are
+// checked and turned back into the usual nodes, so the returned trie doesn't
+// keep a reference to data. Use NewFlatTrie to search data without copying
+// it.
+func DecodeTrie(data []uint32) (*Trie, error) {
+	if len(data) < flatHeaderLen || data[0] != flatMagic {
+		return nil, ErrBadEncoding
+	}
+	if data[1] != flatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrBadEncoding, data[1])
+	}
+
+	records := int(data[3])
+	if records < 1 || len(data) != flatHeaderLen+records*flatRecordLen {
+		return nil, fmt.Errorf("%w: expected %d records", ErrBadEncoding, records)
+	}
+
+	t := New()
+	t.reverse = data[2]&flatReverse != 0
+	t.trimSpace = data[2]&flatTrimSpace != 0
+
+	nodes := make([]*node, records)
+	nodes[0] = t.root
+
+	for i := 0; i < records; i++ {
+		rec := data[flatHeaderLen+i*flatRecordLen:]
+		n := nodes[i]
+		if n == nil {
+			return nil, fmt.Errorf("%w: record %d has no parent", ErrBadEncoding, i)
+		}
+
+		if rec[1]&flatTerminal != 0 {
+			n.isTerminated = true
+			n.occurrences = int(rec[3])
+			if n.occurrences < 1 {
+				n.occurrences = 1
+			}
+			t.count++
+			t.total += n.occurrences
+		}
+
+		first, children := int(rec[2]), int(rec[1]>>1)
+		if children > 0 && (first <= i || first+children > records) {
+			return nil, fmt.Errorf("%w: record %d has children out of range", ErrBadEncoding, i)
+		}
+
+		for c := first; c < first+children; c++ {
+			if nodes[c] != nil {
+				return nil, fmt.Errorf("%w: record %d has two parents", ErrBadEncoding, c)
+			}
+			r := rune(data[flatHeaderLen+c*flatRecordLen])
+			if _, ok := n.children[r]; ok {
+				return nil, fmt.Errorf("%w: record %d repeats rune %q", ErrBadEncoding, c, r)
+			}
+			ch := newNode(n, r)
+			n.children[r] = ch
+			nodes[c] = ch
+		}
+	}
+
+	t.maxWordLen = t.root.maxDepth()
+	t.first = t.root.firstRunes()
+
+	return t, nil
+}
+
+// FlatTrie answers lookups directly from the output of Encode, without
+// building any nodes. The records are searched where they are, so opening one
+// costs nothing however large it is, and data can be memory mapped from a
+// file. Words are normalized with strings.ToLower, whatever folder the
+// encoded trie had. data mustn't be changed while the FlatTrie is in use.
+type FlatTrie struct {
+	data      []uint32
+	reverse   bool
+	trimSpace bool
+}
+
+// NewFlatTrie returns a FlatTrie for the output of Encode. Only the header is
+// checked, so that opening is quick; records that turn out to be out of range
+// are treated as missing rather than causing a panic, but a corrupted
+// encoding can give wrong answers where DecodeTrie would return an error.
+func NewFlatTrie(data []uint32) (*FlatTrie, error) {
+	if len(data) < flatHeaderLen || data[0] != flatMagic {
+		return nil, ErrBadEncoding
+	}
+	if data[1] != flatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrBadEncoding, data[1])
+	}
+
+	records := int(data[3])
+	if records < 1 || len(data) != flatHeaderLen+records*flatRecordLen {
+		return nil, fmt.Errorf("%w: expected %d records", ErrBadEncoding, records)
+	}
+
+	return &FlatTrie{
+		data:      data,
+		reverse:   data[2]&flatReverse != 0,
+		trimSpace: data[2]&flatTrimSpace != 0,
+	}, nil
+}
+
+// Find determines if the input string is a word in the encoded trie. Like
+// Trie.Find, it never finds the empty string.
+func (f *FlatTrie) Find(s string) bool {
+	rec, ok := f.walk(s)
+	return ok && rec != flatHeaderLen && f.data[rec+1]&flatTerminal != 0
+}
+
+// HasPrefix determines if there is a word in the encoded trie that starts with
+// the input string.
+func (f *FlatTrie) HasPrefix(s string) bool {
+	rec, ok := f.walk(s)
+	return ok && f.data[rec+1] != 0
+}
+
+// walk follows s down from the root, returning the offset in data of the
+// record it ends at and false if it falls off the trie.
+func (f *FlatTrie) walk(s string) (int, bool) {
+	if f.trimSpace {
+		s = strings.TrimSpace(s)
+	}
+	rs := []rune(strings.ToLower(s))
+	if f.reverse {
+		reverseRunes(rs)
+	}
+
+	records := int(f.data[3])
+	rec := flatHeaderLen
+	for _, r := range rs {
+		first, children := int(f.data[rec+2]), int(f.data[rec+1]>>1)
+		if first+children > records {
+			return 0, false
+		}
+
+		// children are sorted by rune, so they can be binary searched
+		lo, hi := first, first+children
+		for lo < hi {
+			mid := int(uint(lo+hi) >> 1)
+			if rune(f.data[flatHeaderLen+mid*flatRecordLen]) < r {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		if lo == first+children || rune(f.data[flatHeaderLen+lo*flatRecordLen]) != r {
+			return 0, false
+		}
+		rec = flatHeaderLen + lo*flatRecordLen
+	}
+
+	return rec, true
+}
@@ -0,0 +1,208 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTrieEncodeDecode(t *testing.T) {
+
+	list := []string{"tap", "tape", "tap", "top", "zebra", "café"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	got, err := DecodeTrie(trie.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if !reflect.DeepEqual(trie.Words(), got.Words()) {
+		t.Errorf("Expected %v, got %v", trie.Words(), got.Words())
+	}
+
+	if trie.Count() != got.Count() {
+		t.Errorf("Expected count %d, got %d", trie.Count(), got.Count())
+	}
+
+	if trie.Total() != got.Total() {
+		t.Errorf("Expected total %d, got %d", trie.Total(), got.Total())
+	}
+
+	if got.Occurrences("tap") != 2 {
+		t.Errorf("Expected %d occurrences, got %d", 2, got.Occurrences("tap"))
+	}
+
+	if err := got.validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if found, _ := got.IsContained("a tapestry", 0); !found {
+		t.Errorf("For %s Expected %t, got %t", "a tapestry", true, false)
+	}
+
+}
+
+func TestTrieEncodeDecodeReverse(t *testing.T) {
+	trie := New(WithReverse())
+
+	if err := trie.Load([]string{"ing", "ed"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	got, err := DecodeTrie(trie.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if !got.HasSuffix("walking") {
+		t.Errorf("For %s Expected %t, got %t", "walking", true, false)
+	}
+}
+
+func TestDecodeTrieErrors(t *testing.T) {
+
+	trie := New()
+
+	if err := trie.Load([]string{"ab", "ac"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	good := trie.Encode()
+
+	corrupt := func(i int, v uint32) []uint32 {
+		data := append([]uint32(nil), good...)
+		data[i] = v
+		return data
+	}
+
+	cases := []struct {
+		Name string
+		In   []uint32
+	}{
+		{"empty", nil},
+		{"magic", corrupt(0, 0)},
+		{"version", corrupt(1, 99)},
+		{"records", corrupt(3, 9)},
+		{"truncated", good[:len(good)-1]},
+		{"child out of range", corrupt(flatHeaderLen+2, 7)},
+		{"child before parent", corrupt(flatHeaderLen+flatRecordLen+2, 0)},
+		{"repeated rune", corrupt(flatHeaderLen+3*flatRecordLen, 'b')},
+	}
+
+	for _, c := range cases {
+		if _, err := DecodeTrie(c.In); !errors.Is(err, ErrBadEncoding) {
+			t.Errorf("For %s Expected %s, got %v", c.Name, ErrBadEncoding, err)
+		}
+	}
+
+}
+
+func TestFlatTrie(t *testing.T) {
+
+	list := []string{"tap", "tape", "top", "zebra", "café", "a"}
+
+	cases := []struct {
+		In     string
+		Find   bool
+		Prefix bool
+	}{
+		{"tap", true, true},
+		{"TAPE", true, true},
+		{"ta", false, true},
+		{"tapes", false, false},
+		{"caf", false, true},
+		{"café", true, true},
+		{"a", true, true},
+		{"b", false, false},
+		{"", false, true},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Add("topple"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.DeleteNoPrune("topple"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Add(""); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	flat, err := NewFlatTrie(trie.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := flat.Find(c.In); c.Find != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Find, got)
+		}
+		if got := trie.Find(c.In); c.Find != got {
+			t.Errorf("For %s Trie.Find Expected %t, got %t", c.In, c.Find, got)
+		}
+		if got := flat.HasPrefix(c.In); c.Prefix != got {
+			t.Errorf("For %s prefix Expected %t, got %t", c.In, c.Prefix, got)
+		}
+	}
+	if flat.HasPrefix("topp") {
+		t.Errorf("Expected deleted branch to be left out of the encoding")
+	}
+
+	rev := New(WithReverse(), WithTrimSpace())
+	if err := rev.Load([]string{"ing", "ed"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	flat, err = NewFlatTrie(rev.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !flat.Find(" ING ") || !flat.HasPrefix("ng") || flat.HasPrefix("in") {
+		t.Errorf("Expected reverse flat trie to match the ends of words")
+	}
+
+	empty, err := NewFlatTrie(New().Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if empty.Find("") || empty.HasPrefix("") {
+		t.Errorf("Expected empty flat trie to find nothing")
+	}
+
+	bad := trie.Encode()
+	bad[flatHeaderLen+2] = 1 << 20
+	flat, err = NewFlatTrie(bad)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if flat.Find("tap") {
+		t.Errorf("Expected out of range records to be treated as missing")
+	}
+
+	if _, err := NewFlatTrie([]uint32{1, 2, 3}); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("Expected %s, got %v", ErrBadEncoding, err)
+	}
+
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import "sort"
+
+// fuzzyMatch is a word found by an edit distance search
+type fuzzyMatch struct {
+	word        string
+	distance    int
+	occurrences int
+}
+
+// Correct returns up to n words in the trie that are within maxDist edits of
+// the query, for "did you mean" style suggestions. Closer words come first,
+// and words at the same distance are ordered by how many times they have
+// been added, most first.
+func (t *Trie) Correct(query string, maxDist, n int) []string {
+	if t == nil {
+		return []string{}
+	}
+
+	matches := t.root.fuzzy(t.runes(query), maxDist, t.word)
+	sortMatches(matches)
+
+	results := []string{}
+	for _, m := range matches {
+		if len(results) == n {
+			break
+		}
+		results = append(results, m.word)
+	}
+
+	return results
+}
+
+// Nearest returns the k words in the trie closest to query by edit distance,
+// however far away they are, with the distance as their score. It is ordered
+// the same way as Correct. The search starts with only exact matches and
+// doubles the distance allowed until it has k words, so near misses don't
+// need most of the trie explored.
+func (t *Trie) Nearest(query string, k int) []ScoredWord {
+	results := []ScoredWord{}
+	if t == nil || k < 1 {
+		return results
+	}
+
+	rs := t.runes(query)
+
+	// no word can be further away than it would take to replace every rune
+	// of the longer of the two
+	bound := max(len(rs), t.maxWordLen)
+
+	var matches []fuzzyMatch
+	for maxDist := 0; ; maxDist = max(1, maxDist*2) {
+		matches = t.root.fuzzy(rs, maxDist, t.word)
+		if len(matches) >= k || maxDist >= bound {
+			break
+		}
+	}
+	sortMatches(matches)
+
+	for _, m := range matches {
+		if len(results) == k {
+			break
+		}
+		results = append(results, ScoredWord{m.word, float64(m.distance)})
+	}
+
+	return results
+}
+
+// FindClosest returns the word in the trie closest to query by edit distance,
+// along with the distance, for a single "did you mean" suggestion. Ties are
+// broken as in Correct. It returns "" and -1 if the trie is empty.
+func (t *Trie) FindClosest(query string) (string, int) {
+	nearest := t.Nearest(query, 1)
+	if len(nearest) == 0 {
+		return "", -1
+	}
+
+	return nearest[0].Word, int(nearest[0].Score)
+}
+
+// HammingFind returns, in lexical order, the words in the trie that are the
+// same length as query and differ from it in at most maxSubs positions. Only
+// substitutions are allowed, which suits fixed length codes better than the
+// edit distance used by Correct.
+func (t *Trie) HammingFind(query string, maxSubs int) []string {
+	results := []string{}
+	if t == nil || maxSubs < 0 {
+		return results
+	}
+
+	rs := t.runes(query)
+	if len(rs) == 0 {
+		return results
+	}
+
+	t.root.hamming(rs, maxSubs, []rune{}, func(word []rune) {
+		results = append(results, t.word(word))
+	})
+
+	return results
+}
+
+// hamming calls fn with each word below n that matches the rest of query with
+// no more than subs runes changed, where sofar is the path from the root to n.
+func (n *node) hamming(query []rune, subs int, sofar []rune, fn func(word []rune)) {
+	if len(query) == 0 {
+		if n.isTerminated {
+			fn(sofar)
+		}
+		return
+	}
+
+	for _, r := range n.sortedKeys() {
+		left := subs
+		if r != query[0] {
+			if left == 0 {
+				continue
+			}
+			left--
+		}
+		n.children[r].hamming(query[1:], left, append(sofar, r), fn)
+	}
+}
+
+// sortMatches orders matches closest first, then by occurrences, most first,
+// leaving words that tie on both in the order they were found.
+func sortMatches(matches []fuzzyMatch) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].occurrences > matches[j].occurrences
+	})
+}
+
+// fuzzy returns, in lexical order, every word below n within maxDist edits of
+// query by Levenshtein distance. Each node extends the previous row of the
+// edit distance table by one rune, and branches are abandoned once every
+// entry in the row is over maxDist. Words are built from their runes by word.
+func (n *node) fuzzy(query []rune, maxDist int, word func([]rune) string) []fuzzyMatch {
+	matches := []fuzzyMatch{}
+	if maxDist < 0 {
+		return matches
+	}
+
+	row := make([]int, len(query)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	if n.isTerminated && row[len(query)] <= maxDist {
+		matches = append(matches, fuzzyMatch{"", row[len(query)], n.occurrences})
+	}
+
+	for _, r := range n.sortedKeys() {
+		n.children[r].fuzzyStep(query, maxDist, []rune{r}, row, word, &matches)
+	}
+
+	return matches
+}
+
+func (n *node) fuzzyStep(query []rune, maxDist int, sofar []rune, prev []int, word func([]rune) string, matches *[]fuzzyMatch) {
+	row := make([]int, len(prev))
+	row[0] = prev[0] + 1
+	best := row[0]
+
+	for i := 1; i < len(row); i++ {
+		cost := 1
+		if query[i-1] == n.value {
+			cost = 0
+		}
+		row[i] = min(row[i-1]+1, prev[i]+1, prev[i-1]+cost)
+		best = min(best, row[i])
+	}
+
+	if n.isTerminated && row[len(query)] <= maxDist {
+		*matches = append(*matches, fuzzyMatch{word(sofar), row[len(query)], n.occurrences})
+	}
+
+	if best > maxDist {
+		return
+	}
+
+	for _, r := range n.sortedKeys() {
+		n.children[r].fuzzyStep(query, maxDist, append(sofar, r), row, word, matches)
+	}
+}
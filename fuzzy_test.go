@@ -0,0 +1,159 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrieCorrect(t *testing.T) {
+
+	list := []string{"copy", "copper", "cope", "cope", "cope", "cop", "coy", "coy", "work", "workflow"}
+
+	cases := []struct {
+		In      string
+		MaxDist int
+		N       int
+		Out     []string
+	}{
+		{"copy", 0, 5, []string{"copy"}},
+		{"copx", 1, 5, []string{"cope", "cop", "copy"}},
+		{"copx", 1, 2, []string{"cope", "cop"}},
+		{"cpy", 1, 5, []string{"coy", "copy"}},
+		{"cpy", 2, 5, []string{"coy", "copy", "cope", "cop"}},
+		{"WRK", 1, 5, []string{"work"}},
+		{"xyz", 1, 5, []string{}},
+		{"copy", -1, 5, []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := trie.Correct(c.In, c.MaxDist, c.N)
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s within %d Expected %v, got %v", c.In, c.MaxDist, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieNearest(t *testing.T) {
+
+	list := []string{"copy", "copper", "cope", "cope", "cop", "coy", "work", "workflow"}
+
+	cases := []struct {
+		In  string
+		K   int
+		Out []ScoredWord
+	}{
+		{"copy", 1, []ScoredWord{{"copy", 0}}},
+		{"copx", 2, []ScoredWord{{"cope", 1}, {"cop", 1}}},
+		{"wrkflw", 1, []ScoredWord{{"workflow", 2}}},
+		{"zzzzzzzzzzzz", 1, []ScoredWord{{"cope", 12}}},
+		{"COPPERS", 2, []ScoredWord{{"copper", 1}, {"cope", 3}}},
+		{"cop", 0, []ScoredWord{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.Nearest(c.In, c.K); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %q Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+	if got := trie.Nearest("x", 100); len(got) != trie.Count() {
+		t.Errorf("Expected all %d words, got %d", trie.Count(), len(got))
+	}
+
+}
+
+func TestTrieHammingFind(t *testing.T) {
+
+	list := []string{"94110", "94111", "94121", "94016", "9411", "941100", "SKU-A1"}
+
+	cases := []struct {
+		In      string
+		MaxSubs int
+		Out     []string
+	}{
+		{"94110", 0, []string{"94110"}},
+		{"94110", 1, []string{"94110", "94111"}},
+		{"94110", 2, []string{"94016", "94110", "94111", "94121"}},
+		{"9411", 1, []string{"9411"}},
+		{"sku-b1", 1, []string{"sku-a1"}},
+		{"00000", 2, []string{}},
+		{"94110", -1, []string{}},
+		{"", 3, []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.HammingFind(c.In, c.MaxSubs); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %q %d Expected %v, got %v", c.In, c.MaxSubs, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieFindClosest(t *testing.T) {
+
+	list := []string{"build", "bench", "clean", "test", "test"}
+
+	cases := []struct {
+		In       string
+		Word     string
+		Distance int
+	}{
+		{"test", "test", 0},
+		{"tset", "test", 2},
+		{"biuld", "build", 2},
+		{"CLEAN", "clean", 0},
+		{"benc", "bench", 1},
+		{"", "test", 4},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		word, dist := trie.FindClosest(c.In)
+		if c.Word != word || c.Distance != dist {
+			t.Errorf("For %q Expected %q %d, got %q %d", c.In, c.Word, c.Distance, word, dist)
+		}
+	}
+
+	if word, dist := New().FindClosest("test"); word != "" || dist != -1 {
+		t.Errorf("For empty trie Expected %q %d, got %q %d", "", -1, word, dist)
+	}
+
+}
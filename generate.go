@@ -0,0 +1,104 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// importPath is the path generated code uses to import this package
+const importPath = "github.com/tpryan/trie"
+
+// reservedNames are the identifiers the generated code relies on, which
+// varName would shadow or clash with.
+var reservedNames = map[string]bool{
+	"_":      true,
+	"trie":   true,
+	"t":      true,
+	"nil":    true,
+	"panic":  true,
+	"string": true,
+}
+
+// GenerateGoSource writes a Go source file for package pkg that declares the
+// words in the trie as a slice called varName, along with a constructor that
+// loads them into a new trie. The constructor is NewVarName for an exported
+// varName and newVarName otherwise. It is intended for use with go:generate
+// so that a dictionary can be compiled in rather than loaded at runtime. The
+// words are stored as the trie normalized them, and loaded with New's
+// defaults. varName can't be one of the names the generated code uses itself,
+// such as trie, t, nil or panic, as the result wouldn't compile.
+func (t *Trie) GenerateGoSource(pkg, varName string, w io.Writer) error {
+	if !token.IsIdentifier(pkg) {
+		return fmt.Errorf("invalid package name: %q", pkg)
+	}
+	if !token.IsIdentifier(varName) {
+		return fmt.Errorf("invalid variable name: %q", varName)
+	}
+	if reservedNames[varName] {
+		return fmt.Errorf("variable name is used by the generated code: %q", varName)
+	}
+
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "// Code generated by trie.GenerateGoSource. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	fmt.Fprintf(buf, "import %q\n\n", importPath)
+
+	fmt.Fprintf(buf, "// %s holds the words loaded by %s\n", varName, constructorName(varName))
+	fmt.Fprintf(buf, "var %s = []string{\n", varName)
+	for _, word := range t.Words() {
+		fmt.Fprintf(buf, "\t%s,\n", strconv.Quote(word))
+	}
+	fmt.Fprintf(buf, "}\n\n")
+
+	fmt.Fprintf(buf, "// %s returns a new trie containing %s\n", constructorName(varName), varName)
+	fmt.Fprintf(buf, "func %s() *trie.Trie {\n", constructorName(varName))
+	fmt.Fprintf(buf, "\tt := trie.New()\n")
+	fmt.Fprintf(buf, "\tfor _, w := range %s {\n", varName)
+	fmt.Fprintf(buf, "\t\tif err := t.Add(w); err != nil {\n")
+	fmt.Fprintf(buf, "\t\t\tpanic(err)\n")
+	fmt.Fprintf(buf, "\t\t}\n")
+	fmt.Fprintf(buf, "\t}\n")
+	fmt.Fprintf(buf, "\treturn t\n")
+	fmt.Fprintf(buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("cannot format generated source: %s", err)
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+// constructorName returns the name of the generated function that builds the
+// trie for varName, keeping it exported only if varName is.
+func constructorName(varName string) string {
+	r, size := utf8.DecodeRuneInString(varName)
+	name := string(unicode.ToUpper(r)) + varName[size:]
+
+	if unicode.IsUpper(r) {
+		return "New" + name
+	}
+	return "new" + name
+}
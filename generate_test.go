@@ -0,0 +1,167 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// trieStub declares the parts of this package that generated code uses, so
+// that it can be type checked without building the package itself.
+const trieStub = `package trie
+
+type Trie struct{}
+
+func New() *Trie { return nil }
+
+func (t *Trie) Add(s string) error { return nil }
+`
+
+// stubImporter provides trieStub as the package at importPath
+type stubImporter struct {
+	pkg *types.Package
+}
+
+func (s stubImporter) Import(path string) (*types.Package, error) {
+	if path != importPath {
+		return nil, fmt.Errorf("unexpected import %q", path)
+	}
+	return s.pkg, nil
+}
+
+// typeCheck parses and type checks generated source against trieStub
+func typeCheck(src []byte) (*ast.File, error) {
+	fset := token.NewFileSet()
+
+	stub, err := parser.ParseFile(fset, "trie.go", trieStub, 0)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := (&types.Config{}).Check(importPath, fset, []*ast.File{stub}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parser.ParseFile(fset, "words.go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	conf := &types.Config{Importer: stubImporter{pkg}}
+	if _, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, nil); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func TestTrieGenerateGoSource(t *testing.T) {
+
+	list := []string{"copy", "copper", "workflow", "workshop", "workbench", "work", "\"quoted\"", "café"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := trie.GenerateGoSource("words", "Forbidden", buf); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	f, err := typeCheck(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Expected generated source to compile, got %s\n%s", err, buf)
+	}
+
+	if f.Name.Name != "words" {
+		t.Errorf("Expected package %s, got %s", "words", f.Name.Name)
+	}
+
+	if f.Scope.Lookup("NewForbidden") == nil {
+		t.Errorf("Expected constructor NewForbidden in\n%s", buf)
+	}
+
+	// Rebuild the trie from the generated word list and make sure it matches.
+	got := New()
+	ast.Inspect(f, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		word, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+		if word != importPath {
+			got.Add(word)
+		}
+		return true
+	})
+
+	if !reflect.DeepEqual(trie.Words(), got.Words()) {
+		t.Errorf("Expected %v, got %v", trie.Words(), got.Words())
+	}
+
+	cases := []struct {
+		Pkg string
+		Var string
+	}{
+		{"my-words", "Forbidden"},
+		{"words", "1forbidden"},
+		{"", "forbidden"},
+		{"words", "_"},
+		{"words", "t"},
+		{"words", "trie"},
+		{"words", "panic"},
+		{"words", "nil"},
+		{"words", "string"},
+	}
+
+	for _, c := range cases {
+		if err := trie.GenerateGoSource(c.Pkg, c.Var, &bytes.Buffer{}); err == nil {
+			t.Errorf("For %s %s Expected error, got nil", c.Pkg, c.Var)
+		}
+	}
+
+	// every predeclared name has to either be rejected or work
+	names := append([]string{"forbidden", "x", "w", "err", "New", "words", "trie2"}, types.Universe.Names()...)
+	for _, name := range names {
+		if reservedNames[name] {
+			continue
+		}
+		buf := &bytes.Buffer{}
+		if err := trie.GenerateGoSource("trie", name, buf); err != nil {
+			t.Errorf("For %s Expected no error, got %s", name, err)
+			continue
+		}
+		if _, err := typeCheck(buf.Bytes()); err != nil {
+			t.Errorf("For %s Expected generated source to compile, got %s\n%s", name, err, buf)
+		}
+	}
+
+	if got := constructorName("forbidden"); got != "newForbidden" {
+		t.Errorf("Expected %s, got %s", "newForbidden", got)
+	}
+
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import "sort"
+
+// Glob returns, in lexical order, the words in the trie matching a shell style
+// pattern. A '*' matches any run of runes including none, and a '?' matches
+// exactly one rune. A literal '*', '?' or '\' is written by putting a '\' in
+// front of it.
+func (t *Trie) Glob(pattern string) []string {
+	if t == nil {
+		return []string{}
+	}
+
+	tokens := parseGlob(t.folded(pattern))
+	if t.reverse {
+		for i, j := 0, len(tokens)-1; i < j; i, j = i+1, j-1 {
+			tokens[i], tokens[j] = tokens[j], tokens[i]
+		}
+	}
+
+	g := &globber{
+		trie:    t,
+		tokens:  tokens,
+		seen:    make(map[globState]bool),
+		results: []string{},
+	}
+
+	g.match(t.root, []rune{}, 0)
+	sort.Strings(g.results)
+
+	return g.results
+}
+
+// globToken is one element of a parsed glob pattern
+type globToken struct {
+	value rune
+	kind  globKind
+}
+
+type globKind int
+
+const (
+	globLiteral globKind = iota
+	globAny
+	globStar
+)
+
+func parseGlob(pattern []rune) []globToken {
+	tokens := []globToken{}
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			tokens = append(tokens, globToken{'*', globStar})
+		case '?':
+			tokens = append(tokens, globToken{'?', globAny})
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			tokens = append(tokens, globToken{pattern[i], globLiteral})
+		default:
+			tokens = append(tokens, globToken{pattern[i], globLiteral})
+		}
+	}
+
+	return tokens
+}
+
+// globState is a node paired with how much of the pattern has been matched
+// on the way to it.
+type globState struct {
+	node  *node
+	token int
+}
+
+// globber keeps track of a Glob search. Remembering the states it has already
+// been in stops a pattern with several stars from exploring, and reporting,
+// the same word more than once.
+type globber struct {
+	trie    *Trie
+	tokens  []globToken
+	seen    map[globState]bool
+	results []string
+}
+
+func (g *globber) match(n *node, sofar []rune, i int) {
+	state := globState{n, i}
+	if g.seen[state] {
+		return
+	}
+	g.seen[state] = true
+
+	if i == len(g.tokens) {
+		if n.isTerminated {
+			g.results = append(g.results, g.trie.word(sofar))
+		}
+		return
+	}
+
+	tok := g.tokens[i]
+	switch tok.kind {
+	case globStar:
+		g.match(n, sofar, i+1)
+		for r, ch := range n.children {
+			g.match(ch, append(sofar, r), i)
+		}
+	case globAny:
+		for r, ch := range n.children {
+			g.match(ch, append(sofar, r), i+1)
+		}
+	default:
+		if ch, ok := n.children[tok.value]; ok {
+			g.match(ch, append(sofar, tok.value), i+1)
+		}
+	}
+}
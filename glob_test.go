@@ -0,0 +1,58 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrieGlob(t *testing.T) {
+
+	list := []string{"copy", "copper", "workflow", "workshop", "workbench", "work", "wok", "a*b", "a?b", "axb"}
+
+	cases := []struct {
+		In  string
+		Out []string
+	}{
+		{"w*k", []string{"wok", "work"}},
+		{"W*K", []string{"wok", "work"}},
+		{"work*", []string{"work", "workbench", "workflow", "workshop"}},
+		{"*o*o*", []string{"workflow", "workshop"}},
+		{"cop??", []string{}},
+		{"cop?", []string{"copy"}},
+		{"cop???", []string{"copper"}},
+		{"*", []string{"a*b", "a?b", "axb", "copper", "copy", "wok", "work", "workbench", "workflow", "workshop"}},
+		{"a?b", []string{"a*b", "a?b", "axb"}},
+		{`a\*b`, []string{"a*b"}},
+		{`a\?b`, []string{"a?b"}},
+		{"space*", []string{}},
+		{"", []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := trie.Glob(c.In)
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The operations written at the start of each journal line
+const (
+	journalAdd    = '+'
+	journalDelete = '-'
+	journalExpire = '@'
+)
+
+// WithJournal writes a line to w for every change made to the trie, so that it
+// can be rebuilt with Replay. Each Add writes "+word", even when the word is
+// already there, so occurrences are kept. AddWithExpiry writes "@time word"
+// instead, with the expiry time in Unix nanoseconds. Each word taken out by
+// Delete, DeleteSet or PurgeExpired writes "-word". Words are written as
+// stored, and are quoted in Go syntax if they are empty, start with a quote,
+// or contain a line break or invalid UTF-8.
+//
+// If writing to w fails the change has still been made, and the error is
+// returned by Add or Delete. Nothing more is written after that, and the
+// error is returned by JournalErr.
+func WithJournal(w io.Writer) Option {
+	return func(t *Trie) {
+		t.journal = w
+	}
+}
+
+// JournalErr returns the first error writing to the journal given to
+// WithJournal, or nil if there hasn't been one.
+func (t *Trie) JournalErr() error {
+	if t == nil {
+		return nil
+	}
+
+	return t.journalErr
+}
+
+// Replay applies every change in a journal written by WithJournal to the trie,
+// in order. Replayed changes aren't written to the trie's own journal. It
+// stops at the first line that can't be applied, returning an error that
+// gives the line number.
+func (t *Trie) Replay(r io.Reader) error {
+	if t == nil {
+		return ErrNilTrie
+	}
+
+	journal := t.journal
+	t.journal = nil
+	defer func() { t.journal = journal }()
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" || len(line) == 1 {
+			return fmt.Errorf("malformed journal line %d: %q", n, line)
+		}
+
+		word := line[1:]
+		var expires int64
+		if line[0] == journalExpire {
+			i := strings.IndexByte(word, ' ')
+			if i < 0 {
+				return fmt.Errorf("malformed journal line %d: %q", n, line)
+			}
+			ns, err := strconv.ParseInt(word[:i], 10, 64)
+			if err != nil || ns == 0 {
+				return fmt.Errorf("malformed journal line %d: %q", n, line)
+			}
+			word, expires = word[i+1:], ns
+		}
+		if strings.HasPrefix(word, `"`) {
+			w, err := strconv.Unquote(word)
+			if err != nil {
+				return fmt.Errorf("malformed journal line %d: %q", n, line)
+			}
+			word = w
+		}
+
+		var err error
+		switch line[0] {
+		case journalAdd:
+			err = t.Add(word)
+		case journalExpire:
+			err = t.AddWithExpiry(word, time.Unix(0, expires))
+		case journalDelete:
+			err = t.Delete(word)
+		default:
+			return fmt.Errorf("malformed journal line %d: %q", n, line)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot replay journal line %d: %s", n, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading journal: %s", err)
+	}
+
+	return nil
+}
+
+// logChange writes the word rs to the journal, if there is one, after op
+func (t *Trie) logChange(op string, rs []rune) error {
+	if t.journal == nil {
+		return nil
+	}
+	if t.journalErr != nil {
+		return t.journalErr
+	}
+
+	word := t.word(rs)
+	if word == "" || word[0] == '"' || strings.ContainsAny(word, "\r\n") || !utf8.ValidString(word) {
+		word = strconv.Quote(word)
+	}
+
+	if _, err := io.WriteString(t.journal, op+word+"\n"); err != nil {
+		t.journalErr = fmt.Errorf("cannot write to journal: %s", err)
+	}
+	return t.journalErr
+}
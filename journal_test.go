@@ -0,0 +1,163 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrieJournalReplay(t *testing.T) {
+
+	journal := &strings.Builder{}
+	trie := New(WithJournal(journal))
+
+	if err := trie.Load([]string{"copy", "Copper", "copy", "work", "line\nbreak", `"quoted"`}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Delete("work"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Delete("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %s, got %v", ErrNotFound, err)
+	}
+	if got := trie.DeleteSet(map[string]struct{}{"copper": {}}); got != 1 {
+		t.Errorf("Expected %d, got %d", 1, got)
+	}
+
+	want := "+copy\n+copper\n+copy\n+work\n+\"line\\nbreak\"\n+\"\\\"quoted\\\"\"\n-work\n-copper\n"
+	if got := journal.String(); want != got {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	restored := New()
+	if err := restored.Replay(strings.NewReader(journal.String())); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if !reflect.DeepEqual(trie.SortedWords(), restored.SortedWords()) {
+		t.Errorf("Expected %v, got %v", trie.SortedWords(), restored.SortedWords())
+	}
+	if restored.Occurrences("copy") != 2 {
+		t.Errorf("Expected %d occurrences, got %d", 2, restored.Occurrences("copy"))
+	}
+	if err := restored.validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	// replaying into a journaled trie doesn't journal it again
+	again := &strings.Builder{}
+	if err := New(WithJournal(again)).Replay(strings.NewReader(journal.String())); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if again.Len() != 0 {
+		t.Errorf("Expected empty journal, got %q", again.String())
+	}
+
+}
+
+func TestTrieReplayMalformed(t *testing.T) {
+
+	cases := []struct {
+		In  string
+		Err string
+	}{
+		{"+copy\n\n+work\n", "malformed journal line 2"},
+		{"+copy\n+\n", "malformed journal line 2"},
+		{"*copy\n", "malformed journal line 1"},
+		{"+\"copy\n", "malformed journal line 1"},
+		{"+copy\n-work\n", "cannot replay journal line 2: word not found"},
+		{"@copy\n", "malformed journal line 1"},
+		{"@soon copy\n", "malformed journal line 1"},
+		{"@0 copy\n", "malformed journal line 1"},
+	}
+
+	for _, c := range cases {
+		err := New().Replay(strings.NewReader(c.In))
+		if err == nil || !strings.Contains(err.Error(), c.Err) {
+			t.Errorf("For %q Expected %q error, got %v", c.In, c.Err, err)
+		}
+	}
+
+}
+
+func TestTrieJournalExpiry(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	clock := WithClock(func() time.Time { return now })
+
+	journal := &strings.Builder{}
+	trie := New(WithJournal(journal), clock)
+	if err := trie.AddWithExpiry("spam", start.Add(time.Minute)); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.AddWithExpiry("", start.Add(time.Hour)); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Add("work"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	want := fmt.Sprintf("@%d spam\n@%d \"\"\n+work\n", start.Add(time.Minute).UnixNano(), start.Add(time.Hour).UnixNano())
+	if got := journal.String(); want != got {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	restored := New(clock)
+	if err := restored.Replay(strings.NewReader(journal.String())); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !restored.Find("spam") || !restored.Find("work") {
+		t.Errorf("Expected spam and work to be found before expiry")
+	}
+
+	now = start.Add(2 * time.Minute)
+	if restored.Find("spam") {
+		t.Errorf("Expected spam to have expired")
+	}
+	if !restored.Find("work") {
+		t.Errorf("Expected work to be permanent")
+	}
+	if got := restored.PurgeExpired(); got != 1 {
+		t.Errorf("Expected %d, got %d", 1, got)
+	}
+
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestTrieJournalWriteError(t *testing.T) {
+	trie := New(WithJournal(failingWriter{}))
+
+	err := trie.Add("copy")
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected 'disk full' error, got %v", err)
+	}
+	if !trie.Find("copy") {
+		t.Errorf("Expected copy to be added despite the journal error")
+	}
+	if trie.JournalErr() != err {
+		t.Errorf("Expected %v, got %v", err, trie.JournalErr())
+	}
+}
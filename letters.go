@@ -0,0 +1,69 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import "sort"
+
+// blankTile is the rune in the letters given to WordsFromLetters that stands
+// for any rune, like a blank tile in Scrabble.
+const blankTile = '?'
+
+// WordsFromLetters returns, in lexical order, the words in the trie that can be
+// spelled using each of the given letters at most once. A '?' in letters is a
+// blank that can stand in for any one rune.
+func (t *Trie) WordsFromLetters(letters string) []string {
+	results := []string{}
+	if t == nil {
+		return results
+	}
+
+	avail := make(map[rune]int)
+	blanks := 0
+	for _, r := range t.folded(letters) {
+		if r == blankTile {
+			blanks++
+			continue
+		}
+		avail[r]++
+	}
+
+	t.root.fromLetters([]rune{}, avail, blanks, func(word []rune) {
+		results = append(results, t.word(word))
+	})
+	sort.Strings(results)
+
+	return results
+}
+
+// fromLetters calls fn with every word below n that can be made from the runes
+// in avail and the number of blanks, where sofar is the path from the root to
+// n. A rune's own tile is used before a blank is spent on it, as that never
+// leaves fewer words reachable.
+func (n *node) fromLetters(sofar []rune, avail map[rune]int, blanks int, fn func(word []rune)) {
+	if n.isTerminated && len(sofar) > 0 {
+		fn(sofar)
+	}
+
+	for r, ch := range n.children {
+		switch {
+		case avail[r] > 0:
+			avail[r]--
+			ch.fromLetters(append(sofar, r), avail, blanks, fn)
+			avail[r]++
+		case blanks > 0:
+			ch.fromLetters(append(sofar, r), avail, blanks-1, fn)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrieWordsFromLetters(t *testing.T) {
+
+	list := []string{"a", "at", "tea", "eat", "tee", "ate", "teat", "cat", "Tact"}
+
+	cases := []struct {
+		In  string
+		Out []string
+	}{
+		{"tea", []string{"a", "at", "ate", "eat", "tea"}},
+		{"TEAT", []string{"a", "at", "ate", "eat", "tea", "teat"}},
+		{"te?", []string{"a", "at", "ate", "eat", "tea", "tee"}},
+		{"??", []string{"a", "at"}},
+		{"xyz", []string{}},
+		{"", []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.WordsFromLetters(c.In); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %q Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+	rev := New(WithReverse())
+	if err := rev.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if got := rev.WordsFromLetters("tca?"); !reflect.DeepEqual([]string{"a", "at", "ate", "cat", "eat", "tact", "tea"}, got) {
+		t.Errorf("For reverse Expected %v, got %v", []string{"a", "at", "ate", "cat", "eat", "tact", "tea"}, got)
+	}
+
+}
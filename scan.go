@@ -0,0 +1,180 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"iter"
+	"strings"
+	"unicode/utf8"
+)
+
+// Match is a word from the trie found in an input string
+type Match struct {
+	// Word is the word as it is stored in the trie
+	Word string
+	// Offset is the byte offset in the input where the match starts
+	Offset int
+}
+
+// Matches returns an iterator over every word in the trie longer than min
+// runes that is contained within the input string, in the order they are
+// found. Matches are found as the iterator is ranged over, so breaking out
+// early skips the rest of the scan and nothing is collected up front.
+func (t *Trie) Matches(s string, min int) iter.Seq[Match] {
+	return func(yield func(Match) bool) {
+		if t == nil {
+			return
+		}
+
+		rs, offsets := t.scanRunes(s)
+		now := t.clock()
+
+		for i := range rs {
+			stop := false
+			t.root.prefixesOf(rs[i:], now, func(length int) bool {
+				if length <= min {
+					return true
+				}
+				start, _ := span(s, offsets[i:i+length])
+				if !yield(Match{t.word(rs[i : i+length]), start}) {
+					stop = true
+				}
+				return !stop
+			})
+			if stop {
+				return
+			}
+		}
+	}
+}
+
+// ReplaceFunc returns a copy of s with each word from the trie found in it
+// replaced by the result of repl. Where words overlap the longest one starting
+// at the earliest position wins, and scanning carries on after it. repl is
+// given the text as it appears in s, before any normalization, and the rest
+// of s is left untouched.
+func (t *Trie) ReplaceFunc(s string, repl func(match string) string) string {
+	if t == nil {
+		return s
+	}
+
+	rs, offsets := t.scanRunes(s)
+	now := t.clock()
+
+	spans := [][2]int{}
+	for i := 0; i < len(rs); {
+		longest := 0
+		t.root.prefixesOf(rs[i:], now, func(length int) bool {
+			longest = length
+			return true
+		})
+
+		if longest == 0 {
+			i++
+			continue
+		}
+
+		start, end := span(s, offsets[i:i+longest])
+		spans = append(spans, [2]int{start, end})
+		i += longest
+	}
+
+	if t.reverse {
+		for i, j := 0, len(spans)-1; i < j; i, j = i+1, j-1 {
+			spans[i], spans[j] = spans[j], spans[i]
+		}
+	}
+
+	b := strings.Builder{}
+	last := 0
+	for _, sp := range spans {
+		if sp[0] < last {
+			continue
+		}
+		b.WriteString(s[last:sp[0]])
+		b.WriteString(repl(s[sp[0]:sp[1]]))
+		last = sp[1]
+	}
+	b.WriteString(s[last:])
+
+	return b.String()
+}
+
+// SplitLongest finds the longest word in the trie that s starts with, and
+// returns it as it appears in s along with the rest of s after it, so that
+// match+rest == s. ok is false, with rest set to s, if no word starts s. For a
+// trie made with WithReverse it is the longest word s ends with, and rest is
+// what comes before it.
+func (t *Trie) SplitLongest(s string) (match string, rest string, ok bool) {
+	if t == nil {
+		return "", s, false
+	}
+
+	rs, offsets := t.scanRunes(s)
+	now := t.clock()
+
+	longest := 0
+	t.root.prefixesOf(rs, now, func(length int) bool {
+		longest = length
+		return true
+	})
+
+	if longest == 0 {
+		return "", s, false
+	}
+
+	start, end := span(s, offsets[:longest])
+	if t.reverse {
+		return s[start:], s[:start], true
+	}
+	return s[:end], s[end:], true
+}
+
+// scanRunes normalizes s a rune at a time, returning the runes in the order
+// they are stored along with the byte offset in s that each one came from.
+// Unlike runes it can tie matches back to the original text, even when the
+// folder changes the number of runes.
+func (t *Trie) scanRunes(s string) ([]rune, []int) {
+	rs := make([]rune, 0, len(s))
+	offsets := make([]int, 0, len(s))
+
+	for i, r := range s {
+		for _, f := range t.folded(string(r)) {
+			rs = append(rs, f)
+			offsets = append(offsets, i)
+		}
+	}
+
+	if t.reverse {
+		reverseRunes(rs)
+		for i, j := 0, len(offsets)-1; i < j; i, j = i+1, j-1 {
+			offsets[i], offsets[j] = offsets[j], offsets[i]
+		}
+	}
+
+	return rs, offsets
+}
+
+// span returns the start and end byte offsets in s covered by a run of
+// offsets from scanRunes.
+func span(s string, offsets []int) (int, int) {
+	start, end := offsets[0], offsets[len(offsets)-1]
+	if start > end {
+		start, end = end, start
+	}
+
+	_, size := utf8.DecodeRuneInString(s[end:])
+	return start, end + size
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trie
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTrieReplaceFunc(t *testing.T) {
+
+	list := []string{"cat", "category", "dog", "a"}
+
+	cases := []struct {
+		In  string
+		Out string
+	}{
+		{"the cat sat", "the [CAT] s[A]t"},
+		{"Category: Dog", "[CATEGORY]: [DOG]"},
+		{"catcat", "[CAT][CAT]"},
+		{"nothing here", "nothing here"},
+		{"", ""},
+		{"ÉCAT", "É[CAT]"},
+	}
+
+	repl := func(match string) string {
+		return "[" + strings.ToUpper(match) + "]"
+	}
+
+	for _, opts := range [][]Option{{}, {WithReverse()}} {
+		trie := New(opts...)
+
+		if err := trie.Load(list); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+
+		for _, c := range cases {
+			got := trie.ReplaceFunc(c.In, repl)
+			if c.Out != got {
+				t.Errorf("For %q Expected %q, got %q", c.In, c.Out, got)
+			}
+		}
+	}
+
+	trie := New(WithFolder(func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(s), "ß", "ss")
+	}))
+	if err := trie.Add("strasse"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	got := trie.ReplaceFunc("die Straße hier", func(match string) string {
+		return "<" + match + ">"
+	})
+	if want := "die <Straße> hier"; want != got {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+}
+
+func TestTrieMatches(t *testing.T) {
+
+	list := []string{"a", "apple", "spam", "copper"}
+
+	cases := []struct {
+		In  string
+		Min int
+		Out []Match
+	}{
+		{"Spam and Apples", 0, []Match{{"spam", 0}, {"a", 2}, {"a", 5}, {"a", 9}, {"apple", 9}}},
+		{"Spam and Apples", 1, []Match{{"spam", 0}, {"apple", 9}}},
+		{"café copper", 3, []Match{{"copper", 6}}},
+		{"nothing", 0, []Match{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := []Match{}
+		for m := range trie.Matches(c.In, c.Min) {
+			got = append(got, m)
+		}
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+	got := []Match{}
+	for m := range trie.Matches("spam spam spam", 0) {
+		got = append(got, m)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if want := []Match{{"spam", 0}, {"a", 2}}; !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+}
+
+func TestTrieSplitLongest(t *testing.T) {
+
+	list := []string{"new", "newyork", "york", "city", "ÉTÉ"}
+
+	cases := []struct {
+		In    string
+		Match string
+		Rest  string
+		OK    bool
+	}{
+		{"NewYorkCity", "NewYork", "City", true},
+		{"newyorker", "newyork", "er", true},
+		{"news", "new", "s", true},
+		{"étéyork", "été", "york", true},
+		{"city", "city", "", true},
+		{"xnew", "", "xnew", false},
+		{"", "", "", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		match, rest, ok := trie.SplitLongest(c.In)
+		if c.Match != match || c.Rest != rest || c.OK != ok {
+			t.Errorf("For %q Expected %q %q %t, got %q %q %t", c.In, c.Match, c.Rest, c.OK, match, rest, ok)
+		}
+	}
+
+	tokens := []string{}
+	for rest := "newyorkcitycity"; rest != ""; {
+		match, r, ok := trie.SplitLongest(rest)
+		if !ok {
+			t.Fatalf("For %q Expected a match", rest)
+		}
+		tokens = append(tokens, match)
+		rest = r
+	}
+	if want := []string{"newyork", "city", "city"}; !reflect.DeepEqual(want, tokens) {
+		t.Errorf("Expected %v, got %v", want, tokens)
+	}
+
+	rev := New(WithReverse())
+	if err := rev.Load([]string{"ing", "walking"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if match, rest, ok := rev.SplitLongest("sleepwalking"); match != "walking" || rest != "sleep" || !ok {
+		t.Errorf("For reverse Expected %q %q, got %q %q", "walking", "sleep", match, rest)
+	}
+
+}
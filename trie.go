@@ -15,128 +15,2190 @@
 package trie
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"os"
+	"slices"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // ErrTrieLoadEmpty is thrown when you try and load an empty slice of strings
 // to the trie
 var ErrTrieLoadEmpty = errors.New("cannot load empty slice of strings ")
 
+// ErrNotFound is returned when you try and delete a word that isn't in the
+// trie
+var ErrNotFound = errors.New("word not found in trie")
+
+// ErrNilTrie is returned when you try and change a trie that is nil rather
+// than made with New
+var ErrNilTrie = errors.New("cannot change a nil trie")
+
+// ErrInvalidUTF8 is returned when a trie made with WithValidateUTF8 is given
+// a word that isn't valid UTF-8
+var ErrInvalidUTF8 = errors.New("word is not valid utf-8")
+
+// ErrStepBudget is returned when a search gives up because it has visited as
+// many nodes as it was allowed to
+var ErrStepBudget = errors.New("search step budget exhausted")
+
 // Trie is a tree like data structure that allows us to process string finding
-// operations faster than other means.
+// operations faster than other means. Lookups on a nil *Trie act as if it were
+// empty, and changes to it return ErrNilTrie.
 type Trie struct {
-	root  *node
-	count int
+	root      *node
+	count     int
+	total     int
+	fold      func(string) string // strings.ToLower when nil
+	reverse   bool
+	trimSpace bool
+	validUTF8 bool
+
+	// maxWordLen is the length in runes of the longest word added. It isn't
+	// lowered when words are deleted, so it may be more than the longest
+	// word still in the trie until Compact or RecomputeCount works it out
+	// again.
+	maxWordLen int
+
+	// first holds the ASCII runes that start a word, so IsContained can skip
+	// positions that can't match without a map lookup. It is added to by Add
+	// and rebuilt whenever the root is replaced, so lookups only read it.
+	// Like maxWordLen it isn't updated when words are deleted.
+	first asciiSet
+
+	// now is the clock used for expiry, and expiring is set once a word has
+	// been given an expiry time, so lookups only read the clock when there
+	// is something that could have expired.
+	now      func() time.Time
+	expiring bool
+
+	onChange func(word string, added bool)
+
+	// journal is where mutations are logged for Replay, and journalErr
+	// is the first error writing to it, after which nothing more is written.
+	journal    io.Writer
+	journalErr error
+
+	// insertionOrder is set by WithInsertionOrder, and nextOrder is the
+	// index the next new word will be given.
+	insertionOrder bool
+	nextOrder      int
+
+	// separator splits words into segments for WithSeparator, or is 0
+	separator rune
+
+	// equiv maps runes to the one they are treated as, after folding
+	equiv map[rune]rune
+
+	// automaton holds the failure links for ScanPositions. Adding or deleting
+	// a word sets it back to nil, marking the links as out of date. It is
+	// swapped atomically as ScanPositions builds it while only reading.
+	automaton *atomic.Pointer[automaton]
+}
+
+// asciiSet is a bitset of ASCII runes
+type asciiSet [2]uint64
+
+func (s *asciiSet) add(r rune) {
+	if r >= 0 && r < 128 {
+		s[r/64] |= 1 << (r % 64)
+	}
+}
+
+// mayContain reports false only for ASCII runes that aren't in the set.
+// Anything else has to be checked some other way.
+func (s *asciiSet) mayContain(r rune) bool {
+	return r < 0 || r >= 128 || s[r/64]&(1<<(r%64)) != 0
+}
+
+// firstRunes returns the set of ASCII runes that the children of n are for,
+// which for the root are the runes that start a word.
+func (n *node) firstRunes() asciiSet {
+	var s asciiSet
+	for r := range n.children {
+		s.add(r)
+	}
+	return s
+}
+
+// Option configures optional behavior of a trie when passed to New
+type Option func(*Trie)
+
+// WithFolder replaces strings.ToLower as the function used to normalize words
+// before they are added or looked up. It allows for locale aware matching,
+// for instance with golang.org/x/text/cases. strings.ToLower only maps one
+// rune to another, so full case folding such as cases.Fold().String is
+// needed for 'ß' to match "SS".
+func WithFolder(fold func(string) string) Option {
+	return func(t *Trie) {
+		t.fold = fold
+	}
+}
+
+// WithReverse stores words back to front, so that lookups match the ends of
+// words instead of the starts. Find works as normal, IsContained still finds
+// words anywhere, and HasSuffix becomes a fast walk rather than a scan.
+// Prefixes passed to methods such as Complete or HasPrefix match the ends of
+// words instead. Words are always handed back the right way round, but
+// enumerations are ordered by the reversed words.
+func WithReverse() Option {
+	return func(t *Trie) {
+		t.reverse = true
+	}
+}
+
+// WithTrimSpace trims leading and trailing white space from words before they
+// are added, looked up or deleted, so " copper " is the same as "copper".
+func WithTrimSpace() Option {
+	return func(t *Trie) {
+		t.trimSpace = true
+	}
+}
+
+// WithValidateUTF8 makes Add return ErrInvalidUTF8, without changing the trie,
+// for words that aren't valid UTF-8. Otherwise the bad bytes are stored as
+// utf8.RuneError and match any other invalid input.
+func WithValidateUTF8() Option {
+	return func(t *Trie) {
+		t.validUTF8 = true
+	}
+}
+
+// WithOnChange calls fn after each word is added to or removed from the trie,
+// with the word as it is stored, so after folding. Adding a word that is
+// already there, or deleting one that isn't, doesn't count as a change. fn
+// is called for words taken out by DeleteSet and PurgeExpired as well as by
+// Delete.
+func WithOnChange(fn func(word string, added bool)) Option {
+	return func(t *Trie) {
+		t.onChange = fn
+	}
+}
+
+// WithInsertionOrder numbers each word as it is added, so WordsByInsertion can
+// hand them back in the order they went in. A word that is deleted and then
+// added again goes to the back.
+func WithInsertionOrder() Option {
+	return func(t *Trie) {
+		t.insertionOrder = true
+	}
+}
+
+// WithSeparator treats words as paths of segments split by sep, such as the
+// dotted keys "service.api.v1". HasPrefix, Complete and CompleteDistinct then
+// only accept prefixes made of whole segments, so "service.api" is a prefix
+// of that key but "service.ap" isn't. A prefix may end with sep. Other
+// methods work on runes as usual.
+func WithSeparator(sep rune) Option {
+	return func(t *Trie) {
+		t.separator = sep
+	}
+}
+
+// WithRuneEquivalence treats each rune that is a key in equiv as the rune it
+// maps to, wherever words are normalized, so with '0' mapped to 'o' a stored
+// "copper" is found in "c0pper". The mapping is applied after folding, so
+// keys should be in folded form, and it isn't applied again to its own
+// results. Words are stored and handed back in their mapped form.
+func WithRuneEquivalence(equiv map[rune]rune) Option {
+	return func(t *Trie) {
+		t.equiv = make(map[rune]rune, len(equiv))
+		for k, v := range equiv {
+			t.equiv[k] = v
+		}
+	}
+}
+
+// New returns a new initialized trie
+func New(opts ...Option) *Trie {
+	root := newNode(nil, rune(0))
+	t := &Trie{root: root, now: time.Now, automaton: new(atomic.Pointer[automaton])}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// runes normalizes s the same way as every word in the trie and splits it
+// into runes in the order they are stored.
+func (t *Trie) runes(s string) []rune {
+	if t.trimSpace {
+		s = strings.TrimSpace(s)
+	}
+
+	rs := t.folded(s)
+	if t.reverse {
+		reverseRunes(rs)
+	}
+	return rs
+}
+
+// appendRunes works like runes but appends to buf, so a caller can reuse the
+// same buffer without allocating.
+func (t *Trie) appendRunes(buf []rune, s string) []rune {
+	if t.trimSpace {
+		s = strings.TrimSpace(s)
+	}
+
+	// lowering a rune at a time means mixed case input doesn't need a new
+	// string, which only a custom folder can't avoid
+	start := len(buf)
+	if t.fold == nil {
+		for _, r := range s {
+			buf = append(buf, unicode.ToLower(r))
+		}
+	} else {
+		for _, r := range t.fold(s) {
+			buf = append(buf, r)
+		}
+	}
+	t.equate(buf[start:])
+	if t.reverse {
+		reverseRunes(buf[start:])
+	}
+
+	return buf
+}
+
+// normRunes works like runes for a word that has already been split into runes,
+// and leaves rs as it is. Unless WithFolder was used it folds a rune at a time
+// rather than going through a string.
+func (t *Trie) normRunes(rs []rune) []rune {
+	if t.trimSpace {
+		rs = trimSpaceRunes(rs)
+	}
+
+	var out []rune
+	if t.fold == nil {
+		out = make([]rune, len(rs))
+		for i, r := range rs {
+			out[i] = unicode.ToLower(r)
+		}
+		t.equate(out)
+	} else {
+		out = t.folded(string(rs))
+	}
+	if t.reverse {
+		reverseRunes(out)
+	}
+	return out
+}
+
+// trimSpaceRunes is strings.TrimSpace for a slice of runes
+func trimSpaceRunes(rs []rune) []rune {
+	for len(rs) > 0 && unicode.IsSpace(rs[0]) {
+		rs = rs[1:]
+	}
+	for len(rs) > 0 && unicode.IsSpace(rs[len(rs)-1]) {
+		rs = rs[:len(rs)-1]
+	}
+	return rs
+}
+
+// validRunes reports whether every rune in rs could be encoded as UTF-8
+func validRunes(rs []rune) bool {
+	for _, r := range rs {
+		if !utf8.ValidRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// folded normalizes s and splits it into runes, without any reordering
+func (t *Trie) folded(s string) []rune {
+	var rs []rune
+	if t.fold == nil {
+		rs = []rune(strings.ToLower(s))
+	} else {
+		rs = []rune(t.fold(s))
+	}
+	t.equate(rs)
+	return rs
+}
+
+// equate replaces the runes in rs that WithRuneEquivalence maps to another
+func (t *Trie) equate(rs []rune) {
+	if t.equiv == nil {
+		return
+	}
+	for i, r := range rs {
+		if e, ok := t.equiv[r]; ok {
+			rs[i] = e
+		}
+	}
+}
+
+// word turns runes in the order they are stored back into a string the
+// right way round.
+func (t *Trie) word(rs []rune) string {
+	if !t.reverse {
+		return string(rs)
+	}
+
+	w := make([]rune, len(rs))
+	copy(w, rs)
+	reverseRunes(w)
+	return string(w)
+}
+
+func reverseRunes(rs []rune) {
+	for i, j := 0, len(rs)-1; i < j; i, j = i+1, j-1 {
+		rs[i], rs[j] = rs[j], rs[i]
+	}
+}
+
+// Add adds a string to the trie creating any new nodes it needs. Adding a
+// string that is already present leaves the count alone, but is tallied in
+// its occurrences.
+func (t *Trie) Add(s string) error {
+	// fmt.Printf("string addded: %s\n", s)
+	_, err := t.AddPath(s)
+	return err
+}
+
+// AddPath performs Add and returns the runes of the path it took from the
+// root down to the end of the word, after normalization.
+func (t *Trie) AddPath(s string) ([]rune, error) {
+	_, rs, err := t.add(s, 0)
+	return rs, err
+}
+
+// add does the work of AddPath, also returning the node at the end of the
+// word. The word's expiry is set to expires, in Unix nanoseconds, or cleared
+// if that's 0.
+func (t *Trie) add(s string, expires int64) (*node, []rune, error) {
+	if t == nil {
+		return nil, nil, ErrNilTrie
+	}
+	if t.validUTF8 && !utf8.ValidString(s) {
+		return nil, nil, ErrInvalidUTF8
+	}
+
+	return t.addRunes(t.runes(s), expires)
+}
+
+// AddRunes works like Add for a word that has already been split into runes,
+// saving the conversion from a string. The runes are normalized the same way,
+// including by a folder passed to WithFolder, and rs itself isn't changed.
+// With WithValidateUTF8 it returns ErrInvalidUTF8 if a rune in rs can't be
+// encoded as UTF-8.
+func (t *Trie) AddRunes(rs []rune) error {
+	if t == nil {
+		return ErrNilTrie
+	}
+	if t.validUTF8 && !validRunes(rs) {
+		return ErrInvalidUTF8
+	}
+
+	_, _, err := t.addRunes(t.normRunes(rs), 0)
+	return err
+}
+
+// addRunes adds the already normalized word rs, for both add and AddRunes.
+func (t *Trie) addRunes(rs []rune, expires int64) (*node, []rune, error) {
+	n, added, err := t.root.addChild(rs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return n, rs, t.added(n, rs, added, expires)
+}
+
+// added keeps the trie's bookkeeping up to date after the word rs has been
+// marked as ending at n, where isNew says if it wasn't a word before, and
+// gives the word the expiry time expires, or none if that's 0.
+func (t *Trie) added(n *node, rs []rune, isNew bool, expires int64) error {
+	n.expires = expires
+	if expires != 0 {
+		t.expiring = true
+	}
+	if isNew {
+		t.count++
+		t.automaton.Store(nil)
+		if t.insertionOrder {
+			n.order = t.nextOrder
+			t.nextOrder++
+		}
+	}
+	t.total++
+	if len(rs) > t.maxWordLen {
+		t.maxWordLen = len(rs)
+	}
+	if len(rs) > 0 {
+		t.first.add(rs[0])
+	}
+	if isNew && t.onChange != nil {
+		t.onChange(t.word(rs), true)
+	}
+	if expires != 0 {
+		return t.logChange(fmt.Sprintf("%c%d ", journalExpire, expires), rs)
+	}
+	return t.logChange(string(journalAdd), rs)
+}
+
+// Load performs Add on a slice of strings.
+func (t *Trie) Load(list []string) error {
+	return t.LoadWithProgress(list, nil)
+}
+
+// progressInterval is how many words LoadWithProgress adds between reports
+const progressInterval = 1000
+
+// LoadSorted performs Load on a list that is sorted once normalized, which is
+// quicker for large lists. Neighbouring words in a sorted list share long
+// prefixes, so rather than walking each one down from the root it picks up
+// from where the last word and this one part ways. It returns ErrUnsorted at
+// the first word out of order, with the words before it already added. A list
+// for a trie made with WithReverse must be sorted by the reversed words.
+func (t *Trie) LoadSorted(sorted []string) error {
+	if t == nil {
+		return ErrNilTrie
+	}
+	if len(sorted) == 0 {
+		return ErrTrieLoadEmpty
+	}
+
+	path := []*node{t.root}
+	var prev []rune
+
+	for _, s := range sorted {
+		if t.validUTF8 && !utf8.ValidString(s) {
+			return ErrInvalidUTF8
+		}
+
+		rs := t.runes(s)
+		if compareRunes(prev, rs) > 0 {
+			return fmt.Errorf("%w: %q comes after %q", ErrUnsorted, s, t.word(prev))
+		}
+
+		common := 0
+		for common < len(prev) && common < len(rs) && prev[common] == rs[common] {
+			common++
+		}
+
+		path = path[:common+1]
+		n := path[common]
+		for _, r := range rs[common:] {
+			ch, ok := n.children[r]
+			if !ok {
+				ch = newNode(n, r)
+				n.children[r] = ch
+			}
+			path = append(path, ch)
+			n = ch
+		}
+
+		if err := t.added(n, rs, n.terminate(), 0); err != nil {
+			return err
+		}
+		prev = rs
+	}
+
+	return nil
+}
+
+// LoadWithProgress performs Load, calling fn with the number of words added
+// so far and the total every 1000 words, and once more when it is finished.
+func (t *Trie) LoadWithProgress(list []string, fn func(done, total int)) error {
+	if len(list) == 0 {
+		return ErrTrieLoadEmpty
+	}
+
+	for i, v := range list {
+		if err := t.Add(v); err != nil {
+			return err
+		}
+		if fn != nil && ((i+1)%progressInterval == 0 || i+1 == len(list)) {
+			fn(i+1, len(list))
+		}
+	}
+
+	return nil
+}
+
+// LoadChan performs Add on every string received from ch until it is closed.
+// The trie isn't safe for concurrent use, but any number of goroutines can
+// send to ch while LoadChan does the adding. After an error the rest of ch is
+// drained without being added so senders aren't left blocked, and the first
+// error is returned.
+func (t *Trie) LoadChan(ch <-chan string) error {
+	var first error
+
+	for s := range ch {
+		if first != nil {
+			continue
+		}
+		if err := t.Add(s); err != nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// AddStream adds each line read from r to the trie, trimming spaces and
+// skipping blank lines. It returns the number of words added, and can be
+// called again on the same trie to keep taking in words.
+func (t *Trie) AddStream(r io.Reader) (int, error) {
+	if t == nil {
+		return 0, ErrNilTrie
+	}
+
+	added := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if err := t.Add(line); err != nil {
+			return added, err
+		}
+		added++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return added, fmt.Errorf("error reading stream: %s", err)
+	}
+
+	return added, nil
+}
+
+// AddTokens splits s on sep, or on runs of white space if sep is empty, and
+// adds each token that isn't empty. Tokens are added as they are, so use
+// WithTrimSpace if sep may have spaces around it. It returns the number of
+// tokens added.
+func (t *Trie) AddTokens(s, sep string) (int, error) {
+	var tokens []string
+	if sep == "" {
+		tokens = strings.Fields(s)
+	} else {
+		tokens = strings.Split(s, sep)
+	}
+
+	added := 0
+	for _, tok := range tokens {
+		if tok == "" {
+			continue
+		}
+		if err := t.Add(tok); err != nil {
+			return added, err
+		}
+		added++
+	}
+
+	return added, nil
+}
+
+// LoadJSON loads a json array of strings into the trie, so that words can come
+// from any source, such as an embedded file or a network response.
+func (t *Trie) LoadJSON(data []byte) error {
+	list := []string{}
+
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("cannot unmarshall json into []string: %s", err)
+	}
+
+	if err := t.Load(list); err != nil {
+		return fmt.Errorf("error adding strings: %s", err)
+	}
+
+	return nil
+}
+
+// LoadFile loads the contents of a json array of strings into the trie. Files
+// that are gzip compressed are decompressed transparently. Use LoadFileStream
+// to avoid holding the whole list of words in memory, or LoadJSON for json
+// that is already in memory.
+func (t *Trie) LoadFile(name string) error {
+
+	data, err := fileToStringSlice(name)
+
+	if err != nil {
+		return fmt.Errorf("erro converting file to []string: %s", err)
+	}
+
+	if err := t.Load(data); err != nil {
+		return fmt.Errorf("error adding strings: %s", err)
+	}
+
+	return nil
+}
+
+// LoadFileStream works like LoadFile, but adds each word as it is decoded
+// rather than reading the whole array into memory first, which keeps peak
+// memory down for very large files. If the file turns out to be malformed
+// part way through, the words before the problem will already have been
+// added.
+func (t *Trie) LoadFileStream(name string) error {
+	file, r, err := openWordsFile(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("cannot unmarshall json into []string: %s", err)
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("cannot unmarshall json into []string: expected array, got %v", tok)
+	}
+
+	added := 0
+	for dec.More() {
+		var s string
+		if err := dec.Decode(&s); err != nil {
+			return fmt.Errorf("cannot unmarshall json into []string: %s", err)
+		}
+		if err := t.Add(s); err != nil {
+			return fmt.Errorf("error adding strings: %s", err)
+		}
+		added++
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("cannot unmarshall json into []string: %s", err)
+	}
+
+	if added == 0 {
+		return ErrTrieLoadEmpty
+	}
+
+	return nil
+}
+
+// LoadFiles performs LoadFile on each of the named files in turn, stopping at
+// the first one that fails.
+func (t *Trie) LoadFiles(names ...string) error {
+	for _, name := range names {
+		if err := t.LoadFile(name); err != nil {
+			return fmt.Errorf("error loading %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+func fileToStringSlice(name string) ([]string, error) {
+	data := []string{}
+
+	file, r, err := openWordsFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Decoding straight from the file means we never hold the raw bytes and
+	// the decoded slice in memory at the same time.
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, fmt.Errorf("cannot unmarshall json into []string: %s", err)
+	}
+
+	return data, nil
+}
+
+// openWordsFile opens the named file and returns it, to be closed by the
+// caller, along with a reader of its decompressed contents.
+func openWordsFile(name string) (*os.File, io.Reader, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read forbidden words file: %s", err)
+	}
+
+	r, err := decompress(bufio.NewReader(file))
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("cannot decompress gzip file: %s", err)
+	}
+
+	return file, r, nil
+}
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompress wraps r in a gzip reader if the data starts with a gzip header,
+// otherwise r is returned as is.
+func decompress(r *bufio.Reader) (io.Reader, error) {
+	magic, err := r.Peek(len(gzipMagic))
+	if err != nil || !bytes.Equal(magic, gzipMagic) {
+		return r, nil
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return gzipErrReader{gz}, nil
+}
+
+// gzipErrReader labels errors coming out of the gzip stream so they aren't
+// mistaken for json errors once the decoder passes them along.
+type gzipErrReader struct {
+	r io.Reader
+}
+
+func (g gzipErrReader) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("cannot decompress gzip file: %s", err)
+	}
+	return n, err
+}
+
+// Find determines if an input string is exactly matches one present in
+// the trie.
+func (t *Trie) Find(s string) bool {
+	if t == nil {
+		return false
+	}
+
+	rs := t.runes(s)
+	return t.root.isChild(rs, t.clock())
+}
+
+// FindRunes works like Find for a word that has already been split into
+// runes, which are normalized the same way. rs itself isn't changed.
+func (t *Trie) FindRunes(rs []rune) bool {
+	if t == nil {
+		return false
+	}
+
+	return t.root.isChild(t.normRunes(rs), t.clock())
+}
+
+// FindLen works like Find, and also returns the length of the word in runes
+// when it is found, saving a separate count in loops that score matches. The
+// length is that of the word as stored, after any trimming done by
+// WithTrimSpace. When the word isn't found it returns false and 0.
+func (t *Trie) FindLen(s string) (bool, int) {
+	if t == nil {
+		return false, 0
+	}
+
+	rs := t.runes(s)
+	if !t.root.isChild(rs, t.clock()) {
+		return false, 0
+	}
+
+	return true, len(rs)
+}
+
+// FindInto works like Find, but uses buf as scratch space for the runes of the
+// input instead of allocating. buf is only used for the length of the call,
+// and is grown if it is too short, so a caller doing many lookups can hold on
+// to one buffer and pass it each time.
+func (t *Trie) FindInto(s string, buf []rune) bool {
+	if t == nil {
+		return false
+	}
+
+	rs := t.appendRunes(buf[:0], s)
+	return t.root.isChild(rs, t.clock())
+}
+
+// FindBatch performs Find on each of the words, returning the results in the
+// same order. One rune buffer is shared by every lookup, as with FindInto.
+func (t *Trie) FindBatch(words []string) []bool {
+	results := make([]bool, len(words))
+	if t == nil {
+		return results
+	}
+
+	buf := make([]rune, 0, t.maxWordLen)
+	for i, w := range words {
+		buf = t.appendRunes(buf[:0], w)
+		results[i] = t.root.isChild(buf, t.clock())
+	}
+
+	return results
+}
+
+// Contains reports whether the input string is a word in the trie. It is the
+// same as Find, named so the trie can stand in for a set of strings.
+func (t *Trie) Contains(s string) bool {
+	return t.Find(s)
+}
+
+// IsContained determins if there is a string in the trie contained within the
+// input string. It also allows for a minimum length match: min is counted in
+// runes, and only words longer than min runes are reported.
+func (t *Trie) IsContained(s string, min int) (bool, string) {
+	if t == nil {
+		return false, ""
+	}
+
+	rs := t.runes(s)
+	first := t.first
+	now := t.clock()
+
+	for i := range rs {
+		if !first.mayContain(rs[i]) {
+			continue
+		}
+		if result, word, _ := t.root.isChildWithDepth(rs[i:], min, now, -1); result {
+			return true, t.word(word)
+		}
+	}
+
+	return false, ""
+}
+
+// IsContainedBudget is IsContained with a cap on the work done. Every node
+// visited, at every position in the input, counts as a step, and once
+// maxSteps have been taken the search stops and returns ErrStepBudget along
+// with no match, even if the last step happened to finish the search. This
+// bounds the time spent on input crafted to follow long near-miss paths from
+// every position, at the cost of missing a word that would have been found
+// with a bigger budget, so maxSteps should allow for the longest legitimate
+// inputs. A maxSteps of zero or less means no limit.
+func (t *Trie) IsContainedBudget(s string, min, maxSteps int) (bool, string, error) {
+	if t == nil {
+		return false, "", nil
+	}
+	if maxSteps <= 0 {
+		maxSteps = -1
+	}
+
+	rs := t.runes(s)
+	first := t.first
+	now := t.clock()
+
+	for i := range rs {
+		if !first.mayContain(rs[i]) {
+			continue
+		}
+		var result bool
+		var word []rune
+		result, word, maxSteps = t.root.isChildWithDepth(rs[i:], min, now, maxSteps)
+		if result {
+			return true, t.word(word), nil
+		}
+		if maxSteps == 0 {
+			return false, "", ErrStepBudget
+		}
+	}
+
+	return false, "", nil
+}
+
+// FindAll returns every word in the trie contained within the input string
+// that is longer than min runes, in the order they appear. By default all
+// matches are reported, so "apple" yields both "a" and "apple" when both are
+// in the trie. When nonOverlapping is set only the longest word starting at a
+// position is reported and the scan resumes after it, so "spamspam" yields
+// "spam" twice but a shorter word inside a longer match is never seen.
+func (t *Trie) FindAll(s string, min int, nonOverlapping bool) []string {
+	if t == nil {
+		return []string{}
+	}
+
+	rs := t.runes(s)
+	results := []string{}
+	now := t.clock()
+
+	for i := 0; i < len(rs); {
+		longest := 0
+		t.root.prefixesOf(rs[i:], now, func(length int) bool {
+			if length <= min {
+				return true
+			}
+			if !nonOverlapping {
+				results = append(results, t.word(rs[i:i+length]))
+			}
+			longest = length
+			return true
+		})
+
+		if nonOverlapping && longest > 0 {
+			results = append(results, t.word(rs[i:i+longest]))
+			i += longest
+			continue
+		}
+		i++
+	}
+
+	return results
+}
+
+// IsContainedWholeWord works like IsContained but only reports words that
+// aren't part of a longer word in the input, so "ass" isn't found in "class".
+// The start and end of the match must be next to a non-letter or the ends of
+// the input.
+func (t *Trie) IsContainedWholeWord(s string, min int) (bool, string) {
+	return t.IsContainedWholeWordFunc(s, min, isNotLetter)
+}
+
+// IsContainedWholeWordFunc works like IsContainedWholeWord but lets the caller
+// decide which runes count as word boundaries, for input such as snake_case
+// identifiers or paths. A nil isBoundary treats any non-letter as a boundary.
+func (t *Trie) IsContainedWholeWordFunc(s string, min int, isBoundary func(rune) bool) (bool, string) {
+	if t == nil {
+		return false, ""
+	}
+	if isBoundary == nil {
+		isBoundary = isNotLetter
+	}
+
+	rs := t.runes(s)
+
+	found, word := t.root.wholeWord(rs, min, t.clock(), isBoundary)
+	return found, t.word(word)
+}
+
+func isNotLetter(r rune) bool {
+	return !unicode.IsLetter(r)
+}
+
+// IsContainedMinBytes works like IsContained but min is counted in bytes
+// rather than runes, so only words whose UTF-8 encoding is longer than
+// minBytes are reported. Lengths are measured after lowercasing.
+func (t *Trie) IsContainedMinBytes(s string, minBytes int) (bool, string) {
+	if t == nil {
+		return false, ""
+	}
+
+	rs := t.runes(s)
+	now := t.clock()
+
+	for i := range rs {
+		n := t.root
+		size := 0
+		for j, r := range rs[i:] {
+			ch, ok := n.children[r]
+			if !ok {
+				break
+			}
+			size += utf8.RuneLen(r)
+			if ch.live(now) && size > minBytes {
+				return true, t.word(rs[i : i+j+1])
+			}
+			n = ch
+		}
+	}
+
+	return false, ""
+}
+
+// ShortestCompletions returns up to k words from the trie that start with
+// prefix, shortest first and in lexical order within a length. It searches
+// breadth first so it can stop as soon as it has k words without visiting
+// the longer words below.
+func (t *Trie) ShortestCompletions(prefix string, k int) []string {
+	if t == nil {
+		return []string{}
+	}
+
+	rs := t.runes(prefix)
+	results := []string{}
+
+	n := t.root.walk(rs)
+	if n == nil || k < 1 {
+		return results
+	}
+
+	type entry struct {
+		node *node
+		path []rune
+	}
+
+	queue := []entry{{n, rs}}
+	for len(queue) > 0 {
+		e := queue[0]
+		queue = queue[1:]
+
+		if e.node.isTerminated {
+			results = append(results, t.word(e.path))
+			if len(results) == k {
+				break
+			}
+		}
+
+		for _, r := range e.node.sortedKeys() {
+			path := make([]rune, len(e.path)+1)
+			copy(path, e.path)
+			path[len(e.path)] = r
+			queue = append(queue, entry{e.node.children[r], path})
+		}
+	}
+
+	return results
+}
+
+// Words returns every word in the trie. Words are always returned in lexical
+// order so the output is the same from one run to the next.
+func (t *Trie) Words() []string {
+	return t.Complete("", 0)
+}
+
+// Diff compares the trie to another one, returning the words only found in t
+// as added and the words only found in other as removed. Both are in lexical
+// order.
+func (t *Trie) Diff(other *Trie) (added, removed []string) {
+	return t.missingFrom(other), other.missingFrom(t)
+}
+
+// UpdatePlan compares the trie to a target list of words and returns what has
+// to change for the trie to hold exactly those words. toAdd has the target
+// words that aren't in the trie, as given and in the order given, with only
+// the first of any that normalize to the same word. toRemove has the words in
+// the trie that aren't in the target, in the same order as Words.
+func (t *Trie) UpdatePlan(target []string) (toAdd, toRemove []string) {
+	toAdd, toRemove = []string{}, []string{}
+	if t == nil {
+		return append(toAdd, target...), toRemove
+	}
+
+	want := make(map[string]bool, len(target))
+	for _, w := range target {
+		key := string(t.runes(w))
+		if want[key] {
+			continue
+		}
+		want[key] = true
+
+		if !t.Find(w) {
+			toAdd = append(toAdd, w)
+		}
+	}
+
+	t.root.collect([]rune{}, func(word []rune) bool {
+		if !want[string(word)] {
+			toRemove = append(toRemove, t.word(word))
+		}
+		return true
+	})
+
+	return toAdd, toRemove
+}
+
+// Apply removes the words in toRemove and then adds the words in toAdd, as
+// returned by UpdatePlan. If any of them fails, the trie is put back the way
+// it was and the error is returned, so either the whole plan is applied or
+// none of it is. This takes a Clone of the trie first, so for a while it
+// needs about twice the memory; ApplyNoRollback avoids that. Anything already
+// passed to WithOnChange or written to a journal before the failure isn't
+// taken back.
+func (t *Trie) Apply(toAdd, toRemove []string) error {
+	if t == nil {
+		return ErrNilTrie
+	}
+
+	snapshot := t.Clone()
+	if err := t.ApplyNoRollback(toAdd, toRemove); err != nil {
+		t.root = snapshot.root
+		t.count = snapshot.count
+		t.total = snapshot.total
+		t.maxWordLen = snapshot.maxWordLen
+		t.nextOrder = snapshot.nextOrder
+		t.first = t.root.firstRunes()
+		t.automaton.Store(nil)
+		return err
+	}
+
+	return nil
+}
+
+// ApplyNoRollback works like Apply but stops at the first failure, leaving
+// whatever changes had been made before it in place.
+func (t *Trie) ApplyNoRollback(toAdd, toRemove []string) error {
+	for _, w := range toRemove {
+		if err := t.Delete(w); err != nil {
+			return fmt.Errorf("cannot remove %q: %w", w, err)
+		}
+	}
+
+	for _, w := range toAdd {
+		if err := t.Add(w); err != nil {
+			return fmt.Errorf("cannot add %q: %w", w, err)
+		}
+	}
+
+	return nil
+}
+
+// Subtree returns a new trie holding the words that start with prefix, with
+// the prefix taken off, so "workshop" under "work" becomes "shop". If prefix
+// is itself a word it becomes the empty word in the new trie, which
+// RootTerminated reports, so that no word is lost. The new trie has the same
+// options as this one, apart from any journal or WithOnChange function, and
+// shares no nodes with it. It returns nil and false if no word starts with
+// prefix. For a trie made with WithReverse the prefix is taken off the ends of
+// the words instead.
+func (t *Trie) Subtree(prefix string) (*Trie, bool) {
+	if t == nil {
+		return nil, false
+	}
+
+	n := t.root.walk(t.runes(prefix))
+	if !n.hasWords() {
+		return nil, false
+	}
+
+	sub := *t
+	sub.root = n.compact(nil)
+	sub.root.value = rune(0)
+	sub.count, sub.total = sub.root.counts()
+	sub.maxWordLen = sub.root.maxDepth()
+	sub.first = sub.root.firstRunes()
+	sub.automaton = new(atomic.Pointer[automaton])
+	sub.onChange = nil
+	sub.journal = nil
+	sub.journalErr = nil
+
+	return &sub, true
+}
+
+// Clone returns a deep copy of the trie that can be changed without affecting
+// the original. It has the same options, and shares any journal writer and
+// WithOnChange function with the original.
+func (t *Trie) Clone() *Trie {
+	if t == nil {
+		return nil
+	}
+
+	c := *t
+	c.root = t.root.compact(nil)
+	if c.root == nil {
+		c.root = newNode(nil, rune(0))
+	}
+	c.first = c.root.firstRunes()
+	c.automaton = new(atomic.Pointer[automaton])
+
+	return &c
+}
+
+// Intersection returns the words found in both t and other. It walks the
+// smaller of the two tries and looks each word up in the other.
+func (t *Trie) Intersection(other *Trie) []string {
+	if t == nil || other == nil {
+		return []string{}
+	}
+
+	small, big := t, other
+	if other.Count() < t.Count() {
+		small, big = other, t
+	}
+
+	results := []string{}
+
+	small.root.collect([]rune{}, func(word []rune) bool {
+		w := small.word(word)
+		if big.Find(w) {
+			results = append(results, w)
+		}
+		return true
+	})
+
+	return results
+}
+
+// missingFrom returns the words in t that aren't in other
+func (t *Trie) missingFrom(other *Trie) []string {
+	results := []string{}
+	if t == nil {
+		return results
+	}
+
+	t.root.collect([]rune{}, func(word []rune) bool {
+		w := t.word(word)
+		if !other.Find(w) {
+			results = append(results, w)
+		}
+		return true
+	})
+
+	return results
+}
+
+// WalkDepth calls fn with every word in the trie, in the same order as Words,
+// along with how many runes deep in the trie it is. Returning false from fn
+// stops the walk.
+func (t *Trie) WalkDepth(fn func(word string, depth int) bool) {
+	if t == nil {
+		return
+	}
+
+	t.root.collect([]rune{}, func(word []rune) bool {
+		return fn(t.word(word), len(word))
+	})
+}
+
+// SortedWords returns every word in the trie in lexical order, without
+// duplicates. It is the same as Words except for a trie made with
+// WithReverse, where the words have to be sorted after they are collected.
+func (t *Trie) SortedWords() []string {
+	if t == nil {
+		return []string{}
+	}
+
+	words := t.Words()
+	if t.reverse {
+		sort.Strings(words)
+	}
+	return words
+}
+
+// HasPrefix determines if there is a word in the trie that starts with the
+// input string.
+func (t *Trie) HasPrefix(s string) bool {
+	if t == nil {
+		return false
+	}
+
+	rs := t.runes(s)
+	n := t.root.walk(rs)
+	return n.hasWords() && t.atBoundary(n, rs)
+}
+
+// atBoundary determines if the prefix rs, which leads to n, is made of whole
+// segments for a trie made with WithSeparator. It is always true otherwise.
+func (t *Trie) atBoundary(n *node, rs []rune) bool {
+	if t.separator == 0 || len(rs) == 0 || rs[len(rs)-1] == t.separator {
+		return true
+	}
+
+	return n.isTerminated || n.children[t.separator].hasWords()
+}
+
+// Complete returns up to limit words from the trie that start with prefix,
+// in lexical order. A limit less than 1 returns every match.
+func (t *Trie) Complete(prefix string, limit int) []string {
+	return t.complete(prefix, limit, false)
+}
+
+// CompleteDistinct works like Complete, but words that only differ by case
+// are collapsed into one. This only matters when a folder that keeps case
+// has been given to New. The word kept is the first in lexical order, which
+// puts upper case ahead of lower case, and limit counts distinct words.
+func (t *Trie) CompleteDistinct(prefix string, limit int) []string {
+	return t.complete(prefix, limit, true)
+}
+
+// ScoredWord is a word from the trie along with how well it matched a query.
+// What the score means depends on the method that returned it.
+type ScoredWord struct {
+	Word  string
+	Score float64
+}
+
+// RankedComplete returns up to n words that start with prefix, best first.
+// A word scores the share of its runes that the prefix covers, so the prefix
+// itself scores 1 and shorter completions rank above longer ones. Ties are in
+// lexical order. If n is less than 1 all completions are returned.
+func (t *Trie) RankedComplete(prefix string, n int) []ScoredWord {
+	results := []ScoredWord{}
+	if t == nil {
+		return results
+	}
+
+	rs := t.runes(prefix)
+
+	start := t.root.walk(rs)
+	if start == nil {
+		return results
+	}
+
+	start.collect(rs, func(word []rune) bool {
+		results = append(results, ScoredWord{t.word(word), float64(len(rs)) / float64(len(word))})
+		return true
+	})
+
+	// collect is already in lexical order, so a stable sort keeps ties that way
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if n > 0 && len(results) > n {
+		results = results[:n]
+	}
+
+	return results
+}
+
+func (t *Trie) complete(prefix string, limit int, distinctFold bool) []string {
+	results := []string{}
+	if t == nil {
+		return results
+	}
+
+	rs := t.runes(prefix)
+	seen := make(map[string]bool)
+
+	n := t.root.walk(rs)
+	if n == nil || !t.atBoundary(n, rs) {
+		return results
+	}
+
+	// with a separator, "a.b" completes to "a.b.c" but not to "a.bc"
+	segment := t.separator != 0 && len(rs) > 0 && rs[len(rs)-1] != t.separator
+
+	n.collect(rs, func(word []rune) bool {
+		if segment && len(word) > len(rs) && word[len(rs)] != t.separator {
+			return true
+		}
+		w := t.word(word)
+		if distinctFold {
+			lower := strings.ToLower(w)
+			if seen[lower] {
+				return true
+			}
+			seen[lower] = true
+		}
+		results = append(results, w)
+		return limit < 1 || len(results) < limit
+	})
+
+	return results
+}
+
+// WordsInLengthRange returns the words in the trie that are between minLen and
+// maxLen runes long inclusive, in lexical order.
+func (t *Trie) WordsInLengthRange(minLen, maxLen int) []string {
+	results := []string{}
+	if t == nil || maxLen < 0 || maxLen < minLen {
+		return results
+	}
+
+	t.root.collectTo([]rune{}, maxLen, func(word []rune) bool {
+		if len(word) >= minLen {
+			results = append(results, t.word(word))
+		}
+		return true
+	})
+
+	return results
+}
+
+// CommonPrefix returns the longest prefix shared by every word in the trie
+func (t *Trie) CommonPrefix() string {
+	if t == nil {
+		return ""
+	}
+
+	prefix := []rune{}
+
+	n := t.root
+	for len(n.children) == 1 && !n.isTerminated {
+		for r, ch := range n.children {
+			prefix = append(prefix, r)
+			n = ch
+		}
+	}
+
+	return t.word(prefix)
+}
+
+// DivergencePoint returns the rune index at which the paths of a and b
+// through the trie split apart. That is the number of runes they start with
+// in common that are also a path in the trie, so it stops early at the point
+// either one leaves the trie.
+func (t *Trie) DivergencePoint(a, b string) int {
+	if t == nil {
+		return 0
+	}
+
+	ra, rb := t.runes(a), t.runes(b)
+
+	n := t.root
+	i := 0
+	for ; i < len(ra) && i < len(rb) && ra[i] == rb[i]; i++ {
+		ch, ok := n.children[ra[i]]
+		if !ok {
+			break
+		}
+		n = ch
+	}
+
+	return i
+}
+
+// ShortestPrefix returns the shortest word in the trie that the input string
+// starts with.
+func (t *Trie) ShortestPrefix(s string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+
+	rs := t.runes(s)
+	now := t.clock()
+
+	n := t.root
+	for i, r := range rs {
+		ch, ok := n.children[r]
+		if !ok {
+			break
+		}
+		if ch.live(now) {
+			return t.word(rs[:i+1]), true
+		}
+		n = ch
+	}
+
+	return "", false
+}
+
+// IsExtensionOf determines if the input string extends a word in the trie, so
+// that a stored "a.b" covers "a.b.c". The word must be shorter than the input,
+// not equal to it, and the longest one that fits is returned.
+func (t *Trie) IsExtensionOf(s string) (bool, string) {
+	if t == nil {
+		return false, ""
+	}
+
+	rs := t.runes(s)
+	now := t.clock()
+
+	longest := 0
+	t.root.prefixesOf(rs, now, func(length int) bool {
+		if length == len(rs) {
+			return false
+		}
+		longest = length
+		return true
+	})
+
+	if longest == 0 {
+		return false, ""
+	}
+	return true, t.word(rs[:longest])
+}
+
+// AnyPrefixMatches reports, for each of the candidates, whether it starts with
+// a word in the trie, in the same order. Unlike IsExtensionOf a candidate that
+// is itself a word counts, and the search for each one stops at the shortest
+// word that fits. One rune buffer is shared by every candidate, as with
+// FindBatch, which makes it well suited to checking a long list against a set
+// of allowed prefixes.
+func (t *Trie) AnyPrefixMatches(candidates []string) []bool {
+	results := make([]bool, len(candidates))
+	if t == nil {
+		return results
+	}
+
+	now := t.clock()
+	found := false
+	stop := func(int) bool {
+		found = true
+		return false
+	}
+
+	buf := make([]rune, 0, t.maxWordLen)
+	for i, c := range candidates {
+		found = false
+		buf = t.appendRunes(buf[:0], c)
+		t.root.prefixesOf(buf, now, stop)
+		results[i] = found
+	}
+
+	return results
+}
+
+// WalkNodes calls fn with every node in the trie below the root, parents before
+// their children and siblings in lexical order. It is given the runes from the
+// root down to the node, whether they are a word in the trie, and how many
+// children the node has. Returning false from fn stops the walk. For a trie
+// made with WithReverse the runes are put the right way round, so they are
+// the ends of words.
+func (t *Trie) WalkNodes(fn func(prefix string, terminal bool, childCount int) bool) {
+	if t == nil {
+		return
+	}
+
+	t.root.walkNodes([]rune{}, func(n *node, path []rune) bool {
+		return fn(t.word(path), n.isTerminated, len(n.children))
+	})
+}
+
+// WalkPrefix follows the input string down the trie calling fn with the text
+// matched so far at every step, and whether it is a word in the trie. It
+// stops when the input runs out or leaves the trie.
+func (t *Trie) WalkPrefix(s string, fn func(word string, terminated bool)) {
+	if t == nil {
+		return
+	}
+
+	rs := t.runes(s)
+
+	n := t.root
+	for i, r := range rs {
+		ch, ok := n.children[r]
+		if !ok {
+			return
+		}
+		fn(t.word(rs[:i+1]), ch.isTerminated)
+		n = ch
+	}
+}
+
+// NextRunes returns, in order, the runes that can follow prefix on the way to
+// a word in the trie.
+func (t *Trie) NextRunes(prefix string) []rune {
+	if t == nil {
+		return []rune{}
+	}
+
+	rs := t.runes(prefix)
+
+	n := t.root.walk(rs)
+	if n == nil {
+		return []rune{}
+	}
+
+	return n.sortedKeys()
+}
+
+// Delete removes a string from the trie, pruning any nodes that no longer
+// lead to a word. It returns ErrNotFound if the string isn't in the trie.
+func (t *Trie) Delete(s string) error {
+	return t.delete(s, true)
+}
+
+// DeleteRunes works like Delete for a word that has already been split into
+// runes, which are normalized the same way. rs itself isn't changed.
+func (t *Trie) DeleteRunes(rs []rune) error {
+	if t == nil {
+		return ErrNilTrie
+	}
+
+	return t.deleteRunes(t.normRunes(rs), true)
+}
+
+// DeleteNoPrune works like Delete, but leaves the nodes of the word in place
+// rather than pruning the ones that no longer lead to a word. Deleting many
+// words this way and then calling Compact once is quicker than pruning after
+// each one, at the cost of holding on to the memory in between. Until then,
+// methods that look at the shape of the trie rather than its words, such as
+// NextRunes, CommonPrefix and NodeCount, still see the branches left behind.
+func (t *Trie) DeleteNoPrune(s string) error {
+	return t.delete(s, false)
+}
+
+func (t *Trie) delete(s string, prune bool) error {
+	if t == nil {
+		return ErrNilTrie
+	}
+
+	return t.deleteRunes(t.runes(s), prune)
+}
+
+// deleteRunes removes the already normalized word rs, for both delete and
+// DeleteRunes.
+func (t *Trie) deleteRunes(rs []rune, prune bool) error {
+	occurrences, err := t.root.remove(rs, prune)
+	if err != nil {
+		return err
+	}
+	t.count--
+	t.total -= occurrences
+	t.automaton.Store(nil)
+	if t.onChange != nil {
+		t.onChange(t.word(rs), false)
+	}
+	return t.logChange(string(journalDelete), rs)
+}
+
+// DeleteMany performs Delete on each of the words, carrying on past any that
+// fail. It returns the result of deleting each word keyed by the word, nil
+// meaning it was removed.
+func (t *Trie) DeleteMany(words ...string) map[string]error {
+	results := make(map[string]error, len(words))
+
+	for _, w := range words {
+		if _, ok := results[w]; ok {
+			continue
+		}
+		results[w] = t.Delete(w)
+	}
+
+	return results
+}
+
+// DeleteSet removes every word in the set from the trie in a single pass,
+// pruning as it goes, and returns the number of words removed. Words in the
+// set that aren't in the trie are ignored. It is quicker than calling Delete
+// for each word when the set is large.
+func (t *Trie) DeleteSet(words map[string]struct{}) int {
+	if t == nil {
+		return 0
+	}
+
+	targets := t.runeSet(words)
+	return t.removeWhere(func(_ *node, word []rune) bool {
+		return targets[string(word)]
+	})
+}
+
+// RetainSet removes every word from the trie that isn't in the set, in a single
+// pass, and returns the number of words removed. Together with DeleteSet it
+// allows for bringing a trie in line with an authoritative list.
+func (t *Trie) RetainSet(words map[string]struct{}) int {
+	if t == nil {
+		return 0
+	}
+
+	keep := t.runeSet(words)
+	return t.removeWhere(func(_ *node, word []rune) bool {
+		return !keep[string(word)]
+	})
+}
+
+// runeSet normalizes each of the words, keyed by their runes as stored
+func (t *Trie) runeSet(words map[string]struct{}) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for w := range words {
+		set[string(t.runes(w))] = true
+	}
+	return set
+}
+
+// removeWhere removes the words for which remove returns true, keeps the
+// counts up to date, and returns how many words were removed.
+func (t *Trie) removeWhere(remove func(n *node, word []rune) bool) int {
+	// the words are only reported once the trie is back in a consistent
+	// state, in case onChange looks at it
+	var gone [][]rune
+	if t.onChange != nil || t.journal != nil {
+		pred := remove
+		remove = func(n *node, word []rune) bool {
+			if !pred(n, word) {
+				return false
+			}
+			gone = append(gone, slices.Clone(word))
+			return true
+		}
+	}
+
+	removed, occurrences := t.root.removeWhere([]rune{}, remove)
+	t.count -= removed
+	t.total -= occurrences
+	if removed > 0 {
+		t.automaton.Store(nil)
+	}
+
+	for _, rs := range gone {
+		if t.onChange != nil {
+			t.onChange(t.word(rs), false)
+		}
+		t.logChange(string(journalDelete), rs)
+	}
+	return removed
+}
+
+// Compact rebuilds the trie out of new nodes whose maps are sized to fit,
+// dropping any branches that don't lead to a word. It reclaims the memory a
+// long lived trie holds onto after many adds and deletes.
+func (t *Trie) Compact() {
+	if t == nil {
+		return
+	}
+
+	root := t.root.compact(nil)
+	if root == nil {
+		root = newNode(nil, rune(0))
+	}
+	t.root = root
+	t.maxWordLen = root.maxDepth()
+	t.first = root.firstRunes()
+	t.automaton.Store(nil)
+}
+
+// ShrinkMaps replaces the children map of every node with one sized to fit
+// its current entries. Go maps never give back space once they have grown, so
+// a trie that has had many words added and deleted can hold onto far more
+// than it needs. Unlike Compact it keeps the existing nodes, so a Cursor
+// stays valid, and it leaves in place branches that don't lead to a word.
+func (t *Trie) ShrinkMaps() {
+	if t == nil {
+		return
+	}
+
+	t.root.shrinkMaps()
+}
+
+// Count returns the number of words in the trie
+func (t *Trie) Count() int {
+	if t == nil {
+		return 0
+	}
+	return t.count
+}
+
+// RecomputeCount counts the words in the trie from scratch, stores the result
+// as the trie's count and returns it. The total of occurrences is
+// recalculated at the same time, as is MaxWordLen. It repairs the counts
+// should they drift from the words actually in the trie.
+func (t *Trie) RecomputeCount() int {
+	if t == nil {
+		return 0
+	}
+
+	t.count, t.total = t.root.counts()
+	t.maxWordLen = t.root.maxDepth()
+	return t.count
+}
+
+// MaxWordLen returns the length in runes of the longest word in the trie, after
+// normalization. Deleting words doesn't lower it, so it may be longer than
+// any word left until Compact or RecomputeCount is called.
+func (t *Trie) MaxWordLen() int {
+	if t == nil {
+		return 0
+	}
+
+	return t.maxWordLen
+}
+
+// IsEmpty determines if there are no words in the trie
+func (t *Trie) IsEmpty() bool {
+	return t.Count() == 0
+}
+
+// WordsWithSuffix returns the words in the trie that end with suffix. Without
+// WithReverse every word has to be checked, so it takes time in proportion to
+// the size of the trie, and the words come back in lexical order. A trie made
+// with WithReverse walks straight to the suffix instead, and orders the words
+// by their reversed form.
+func (t *Trie) WordsWithSuffix(suffix string) []string {
+	results := []string{}
+	if t == nil {
+		return results
+	}
+
+	if t.reverse {
+		return t.complete(suffix, 0, false)
+	}
+
+	rs := t.runes(suffix)
+	t.root.collect(nil, func(word []rune) bool {
+		if len(word) >= len(rs) && slices.Equal(word[len(word)-len(rs):], rs) {
+			results = append(results, t.word(word))
+		}
+		return true
+	})
+
+	return results
+}
+
+// WordsByInsertion returns the words in the trie in the order they were first
+// added, for a trie made with WithInsertionOrder. Otherwise, or for words
+// restored by UnmarshalJSON or DecodeTrie, which don't keep the order, they
+// are in lexical order.
+func (t *Trie) WordsByInsertion() []string {
+	type ordered struct {
+		word  string
+		order int
+	}
+
+	results := []string{}
+	if t == nil {
+		return results
+	}
+
+	words := []ordered{}
+	t.root.terminated([]rune{}, func(n *node, word []rune) {
+		words = append(words, ordered{t.word(word), n.order})
+	})
+
+	sort.SliceStable(words, func(i, j int) bool {
+		if words[i].order != words[j].order {
+			return words[i].order < words[j].order
+		}
+		return words[i].word < words[j].word
+	})
+
+	for _, w := range words {
+		results = append(results, w.word)
+	}
+
+	return results
+}
+
+// GroupByFirstRune returns the words in the trie grouped by the rune they start
+// with, after normalization, each group in lexical order. The groups are the
+// subtrees of the root, so no separate pass over the words is needed. For a
+// trie made with WithReverse they are grouped by the rune they end with. An
+// empty word isn't in any group.
+func (t *Trie) GroupByFirstRune() map[rune][]string {
+	groups := make(map[rune][]string)
+	if t == nil {
+		return groups
+	}
+
+	for r, ch := range t.root.children {
+		words := []string{}
+		ch.collect([]rune{r}, func(word []rune) bool {
+			words = append(words, t.word(word))
+			return true
+		})
+		groups[r] = words
+	}
+
+	return groups
+}
+
+// PrefixCounts returns, for each distinct prefix of n runes, how many words in
+// the trie start with it. Words shorter than n runes are left out rather than
+// grouped under their own length, so the counts may add up to less than
+// Count. For a trie made with WithReverse the prefixes are of the reversed
+// words, and so are the ends of the words. If n is less than 1 the map is
+// empty.
+func (t *Trie) PrefixCounts(n int) map[string]int {
+	counts := make(map[string]int)
+	if t == nil || n < 1 {
+		return counts
+	}
+
+	t.root.atDepth([]rune{}, n, func(nd *node, prefix []rune) {
+		c, _ := nd.counts()
+		counts[t.word(prefix)] = c
+	})
+
+	return counts
+}
+
+// WordsOfLength returns the words in the trie that are exactly n runes long,
+// in lexical order. Only the first n levels of the trie are visited, so it is
+// quicker than filtering Words when n is small. If there are none, or n is
+// negative, the slice is empty.
+func (t *Trie) WordsOfLength(n int) []string {
+	words := []string{}
+	if t == nil || n < 0 {
+		return words
+	}
+
+	t.root.atDepth([]rune{}, n, func(nd *node, word []rune) {
+		if nd.isTerminated {
+			words = append(words, t.word(word))
+		}
+	})
+	sort.Strings(words)
+
+	return words
 }
 
-// New returns a new initialized trie
-func New() *Trie {
-	root := newNode(nil, rune(0))
-	return &Trie{root, 0}
+// WordsContaining returns the words in the trie that contain sub anywhere in
+// them. A trie only indexes the starts of words, so every word has to be
+// checked and it takes time in proportion to the size of the trie. An index
+// of every suffix, such as a suffix automaton, would be needed to do better.
+// The words are in the same order as Words.
+func (t *Trie) WordsContaining(sub string) []string {
+	results := []string{}
+	if t == nil {
+		return results
+	}
+
+	// the runes are compared as stored, which for a trie made with
+	// WithReverse means the reversed word is checked for the reversed sub
+	rs := string(t.runes(sub))
+	t.root.collect(nil, func(word []rune) bool {
+		if strings.Contains(string(word), rs) {
+			results = append(results, t.word(word))
+		}
+		return true
+	})
+
+	return results
 }
 
-// Add adds a string to the trie creating any new nodes it needs
-func (t *Trie) Add(s string) error {
-	// fmt.Printf("string addded: %s\n", s)
-	lower := strings.ToLower(s)
-	rs := []rune(lower)
+// RootTerminated reports whether the empty string is stored as a word, which
+// marks the root of the trie as the end of a word. That happens when Add is
+// given "", or a word that normalizes to it, and is rarely intended, so this
+// allows for checking tries built or saved from unclean data.
+func (t *Trie) RootTerminated() bool {
+	return t != nil && t.root.isTerminated
+}
 
-	if err := t.root.addChild(rs); err != nil {
-		return err
+// TotalRunes returns the number of runes in all of the words in the trie put
+// together. Shared prefixes are counted once for every word they are in.
+func (t *Trie) TotalRunes() int {
+	if t == nil {
+		return 0
 	}
-	t.count++
-	return nil
+
+	return t.root.totalRunes(0)
 }
 
-// Load performs Add on a slice of strings.
-func (t *Trie) Load(list []string) error {
-	if len(list) == 0 {
-		return ErrTrieLoadEmpty
+// BranchingHistogram returns how many nodes in the trie have each number of
+// children, keyed by the number of children. The root is included, so the
+// counts add up to NodeCount plus one.
+func (t *Trie) BranchingHistogram() map[int]int {
+	hist := make(map[int]int)
+	if t == nil {
+		return hist
 	}
 
-	for _, v := range list {
-		if err := t.Add(v); err != nil {
-			return err
+	t.root.branching(hist)
+	return hist
+}
+
+// NodeCount returns the number of nodes below the root, one for each rune
+// stored. Compared with TotalRunes it shows how much space sharing prefixes
+// saves.
+func (t *Trie) NodeCount() int {
+	if t == nil {
+		return 0
+	}
+
+	return t.root.nodeCount() - 1
+}
+
+// HasSuffix determines if there is a word in the trie that the input string
+// ends with. This is a quick walk for a trie made with WithReverse, and a
+// check of every suffix of the input otherwise.
+func (t *Trie) HasSuffix(s string) bool {
+	if t == nil {
+		return false
+	}
+
+	rs := t.runes(s)
+
+	if t.reverse {
+		now := t.clock()
+		found := false
+		t.root.prefixesOf(rs, now, func(length int) bool {
+			found = true
+			return false
+		})
+		return found
+	}
+
+	for i := range rs {
+		if t.root.isChild(rs[i:], t.clock()) {
+			return true
 		}
 	}
 
-	return nil
+	return false
 }
 
-// LoadFile loads the contents of a json array of strings into the trie
-func (t *Trie) LoadFile(name string) error {
+// RuneFrequency returns how many times each rune appears across all of the
+// words in the trie. It is weighted by word, so a rune in a prefix shared by
+// several words is counted once for each of them, the same as if the words
+// were counted one by one.
+func (t *Trie) RuneFrequency() map[rune]int {
+	freq := make(map[rune]int)
+	if t == nil {
+		return freq
+	}
 
-	data, err := fileToStringSlice(name)
+	t.root.runeFrequency(freq)
+	return freq
+}
 
-	if err != nil {
-		return fmt.Errorf("erro converting file to []string: %s", err)
+// Occurrences returns the number of times a word has been added to the trie
+// since it was last deleted.
+func (t *Trie) Occurrences(s string) int {
+	if t == nil {
+		return 0
 	}
 
-	if err := t.Load(data); err != nil {
-		return fmt.Errorf("error adding strings: %s", err)
+	rs := t.runes(s)
+
+	n := t.root.walk(rs)
+	if n == nil || !n.isTerminated {
+		return 0
+	}
+	return n.occurrences
+}
+
+// Total returns the number of words added to the trie counting repeats, where
+// Count only counts distinct words.
+func (t *Trie) Total() int {
+	if t == nil {
+		return 0
 	}
+	return t.total
+}
 
-	return nil
+// jsonNode is the shape a node takes when the trie is marshalled to json
+type jsonNode struct {
+	Value       string               `json:"value"`
+	Terminated  bool                 `json:"terminated"`
+	Occurrences int                  `json:"occurrences,omitempty"`
+	Children    map[string]*jsonNode `json:"children"`
 }
 
-func fileToStringSlice(name string) ([]string, error) {
-	data := []string{}
+// MarshalJSON encodes the structure of the trie as nested json objects, one
+// for each node.
+func (t *Trie) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return New().MarshalJSON()
+	}
 
-	file, err := ioutil.ReadFile(name)
-	if err != nil {
-		return nil, fmt.Errorf("cannot read forbidden words file: %s", err)
+	return json.Marshal(t.root.toJSON())
+}
+
+// UnmarshalJSON rebuilds a trie from the output of MarshalJSON
+func (t *Trie) UnmarshalJSON(data []byte) error {
+	jn := &jsonNode{}
+	if err := json.Unmarshal(data, jn); err != nil {
+		return err
 	}
 
-	err = json.Unmarshal([]byte(file), &data)
+	root := newNode(nil, rune(0))
+	count, total, err := root.fromJSON(jn)
 	if err != nil {
-		return nil, fmt.Errorf("cannot unmarshall json into []string: %s", err)
+		return err
 	}
 
-	return data, nil
+	t.root = root
+	t.count = count
+	t.total = total
+	t.maxWordLen = root.maxDepth()
+	t.first = root.firstRunes()
+	t.automaton.Store(nil)
+	return nil
 }
 
-// Find determines if an input string is exactly matches one present in
-// the trie.
-func (t *Trie) Find(s string) bool {
-	ls := strings.ToLower(s)
-	rs := []rune(ls)
-	return t.root.isChild(rs)
+// Subtree is the part of a trie below a given prefix. It allows for several
+// queries under the same prefix without walking down to it each time. It
+// shouldn't be used after the trie has changed.
+type Subtree struct {
+	trie   *Trie
+	node   *node
+	prefix []rune
 }
 
-// IsContained determins if there is a string in the trie contained within the
-// input string. It also allows for a minimum length match.
-func (t *Trie) IsContained(s string, min int) (bool, string) {
-	ls := strings.ToLower(s)
-	rs := []rune(ls)
+// Navigate returns the subtree under prefix, and false if there are no words
+// in the trie that start with it.
+func (t *Trie) Navigate(prefix string) (*Subtree, bool) {
+	if t == nil {
+		return nil, false
+	}
 
-	for i := range rs {
-		result, sofar := t.root.isChildWithDepth(rs[i:], min, []rune(""))
-		if result {
-			return true, strings.TrimRight(string(sofar), "\x00")
-		}
+	rs := t.runes(prefix)
+
+	n := t.root.walk(rs)
+	if !n.hasWords() {
+		return nil, false
 	}
 
-	return false, ""
+	return &Subtree{t, n, rs}, true
 }
 
-// Delete removes a string from the trie
-func (t *Trie) Delete(s string) error {
-	ls := strings.ToLower(s)
-	rs := []rune(ls)
-	if err := t.root.remove(rs); err != nil {
+// Words returns all of the words in the subtree in lexical order, including
+// the prefix the subtree sits under.
+func (st *Subtree) Words() []string {
+	results := []string{}
+
+	st.node.collect(append([]rune{}, st.prefix...), func(word []rune) bool {
+		results = append(results, st.trie.word(word))
+		return true
+	})
+
+	return results
+}
+
+// Count returns the number of words in the subtree
+func (st *Subtree) Count() int {
+	count := 0
+
+	st.node.collect([]rune{}, func(word []rune) bool {
+		count++
+		return true
+	})
+
+	return count
+}
+
+// HasPrefix determines if there is a word in the subtree that continues on
+// from the subtree's prefix with the input string.
+func (st *Subtree) HasPrefix(s string) bool {
+	return st.node.walk(st.trie.runes(s)).hasWords()
+}
+
+// validate checks that the internal structure of the trie is consistent. It
+// is meant for tests to call after changing the trie.
+func (t *Trie) validate() error {
+	if t.root.parent != nil {
+		return fmt.Errorf("root has a parent")
+	}
+
+	count, total, err := t.root.validate()
+	if err != nil {
 		return err
 	}
-	t.count--
+
+	if count != t.count {
+		return fmt.Errorf("count is %d but there are %d words", t.count, count)
+	}
+	if total != t.total {
+		return fmt.Errorf("total is %d but there are %d occurrences", t.total, total)
+	}
+
 	return nil
 }
 
-// Count returns the number of words in the trie
-func (t *Trie) Count() int {
-	return t.count
+// Cursor allows for walking the trie one rune at a time, keeping track of
+// where it is between calls.
+type Cursor struct {
+	trie    *Trie
+	current *node
+}
+
+// Cursor returns a new cursor positioned at the root of the trie
+func (t *Trie) Cursor() *Cursor {
+	if t == nil {
+		t = New()
+	}
+	return &Cursor{t, t.root}
+}
+
+// Advance moves the cursor to the child for r. It returns false, and leaves
+// the cursor where it was, if there is no such child. Should the trie's
+// folder turn r into more than one rune, the cursor moves past all of them.
+func (c *Cursor) Advance(r rune) bool {
+	// r is only part of a word, so WithTrimSpace mustn't drop it
+	rs := c.trie.folded(string(r))
+	if len(rs) == 0 {
+		return false
+	}
+	if c.trie.reverse {
+		reverseRunes(rs)
+	}
+
+	ch := c.current.walk(rs)
+	if ch == nil {
+		return false
+	}
+	c.current = ch
+	return true
+}
+
+// Terminated determines if the cursor is sitting at the end of a word in
+// the trie.
+func (c *Cursor) Terminated() bool {
+	return c.current.isTerminated
+}
+
+// Reset moves the cursor back to the root of the trie
+func (c *Cursor) Reset() {
+	c.current = c.trie.root
+}
+
+// String returns the word for the cursor's position, made of the runes it has
+// advanced through since the root. The runes are as stored, so after folding,
+// and are put back the right way round for a trie made with WithReverse.
+func (c *Cursor) String() string {
+	return c.trie.word(c.current.path())
 }
 
 // Node is one item in a trie for computing relationships
@@ -145,101 +2207,496 @@ type node struct {
 	children     map[rune]*node
 	value        rune
 	isTerminated bool
+	occurrences  int
+
+	// expires is when the word ending here stops being found, in Unix
+	// nanoseconds, or 0 if it never does.
+	expires int64
+
+	// order is the position the word ending here was added in, for a trie
+	// made with WithInsertionOrder.
+	order int
 }
 
 func newNode(parent *node, value rune) *node {
 	children := make(map[rune]*node)
-	return &node{parent, children, value, false}
+	return &node{parent, children, value, false, 0, 0, 0}
 }
 
-func (n *node) addChild(value []rune) error {
-	first, rest, _ := breakRuneSlice(value)
-	ch, ok := n.children[first]
-	if !ok {
+// path returns the runes on the way from the root down to n, found by
+// following parent pointers back up.
+func (n *node) path() []rune {
+	var rs []rune
+	for ; n.parent != nil; n = n.parent {
+		rs = append(rs, n.value)
+	}
+	reverseRunes(rs)
+	return rs
+}
 
-		if len(value) == 0 {
-			n.isTerminated = true
-			return nil
+// addChild walks value down from n creating any nodes it needs, and marks the
+// last one as the end of a word. It returns true if that word is new.
+func (n *node) addChild(value []rune) (*node, bool, error) {
+	for _, r := range value {
+		ch, ok := n.children[r]
+		if !ok {
+			ch = newNode(n, r)
+			n.children[r] = ch
 		}
+		n = ch
+	}
+
+	return n, n.terminate(), nil
+}
 
-		ch = newNode(n, first)
-		n.children[first] = ch
+// terminate marks n as the end of a word, adding to its occurrences. It
+// returns true if it wasn't the end of a word already.
+func (n *node) terminate() bool {
+	added := !n.isTerminated
+	n.isTerminated = true
+	n.occurrences++
+	return added
+}
 
+func (n *node) remove(value []rune, prune bool) (int, error) {
+	n = n.walk(value)
+	if n == nil || !n.isTerminated {
+		return 0, ErrNotFound
 	}
 
-	return ch.addChild(rest)
+	occurrences := n.occurrences
+	n.isTerminated = false
+	n.occurrences = 0
+	n.expires = 0
+	if prune {
+		n.prune()
+	}
+	return occurrences, nil
 }
 
-func (n *node) remove(value []rune) error {
-	first, rest, _ := breakRuneSlice(value)
+// prune removes n, and then its ancestors, from the trie for as long as they
+// are neither the end of a word nor on the way to one.
+func (n *node) prune() {
+	for n.parent != nil && !n.isTerminated && len(n.children) == 0 {
+		delete(n.parent.children, n.value)
+		n = n.parent
+	}
+}
 
-	if len(value) == 0 {
-		n.isTerminated = false
-		return nil
+func (n *node) toJSON() *jsonNode {
+	jn := &jsonNode{
+		Terminated:  n.isTerminated,
+		Occurrences: n.occurrences,
+		Children:    make(map[string]*jsonNode, len(n.children)),
 	}
-	ch, ok := n.children[first]
-	if ok {
-		return ch.remove(rest)
+	if n.parent != nil {
+		jn.Value = string(n.value)
 	}
 
-	return fmt.Errorf("could not find the children of node")
+	for r, ch := range n.children {
+		jn.Children[string(r)] = ch.toJSON()
+	}
 
+	return jn
 }
 
-func breakRuneSlice(value []rune) (rune, []rune, rune) {
-	first := rune(0)
-	rest := []rune{}
-	last := rune(0)
+// fromJSON fills in n from jn, returning the number of words and the total
+// occurrences it added.
+func (n *node) fromJSON(jn *jsonNode) (int, int, error) {
+	count, total := 0, 0
 
-	if len(value) != 0 {
-		first = value[0]
+	if jn.Terminated {
+		n.isTerminated = true
+		n.occurrences = jn.Occurrences
+		if n.occurrences < 1 {
+			n.occurrences = 1
+		}
+		count++
+		total += n.occurrences
 	}
 
-	if len(value) > 1 {
-		rest = value[1:]
-		last = value[len(value)-1]
+	for k, jch := range jn.Children {
+		rs := []rune(k)
+		if len(rs) != 1 || jch == nil || jch.Value != k {
+			return 0, 0, fmt.Errorf("invalid child %q in trie json", k)
+		}
+
+		ch := newNode(n, rs[0])
+		n.children[rs[0]] = ch
+
+		c, tot, err := ch.fromJSON(jch)
+		if err != nil {
+			return 0, 0, err
+		}
+		count += c
+		total += tot
 	}
 
-	return first, rest, last
+	return count, total, nil
 }
 
-func (n *node) isChild(value []rune) bool {
+// prefixesOf calls fn with the length of each word below n that value starts
+// with, shortest first, skipping words that have expired by now. It stops as
+// soon as fn returns false.
+func (n *node) prefixesOf(value []rune, now int64, fn func(length int) bool) {
+	for i, r := range value {
+		ch, ok := n.children[r]
+		if !ok {
+			return
+		}
+		if ch.live(now) && !fn(i+1) {
+			return
+		}
+		n = ch
+	}
+}
+
+// wholeWord looks for a word longer than min runes in value that has a
+// boundary rune, or the end of value, on both sides of it, and hasn't expired
+// by now.
+func (n *node) wholeWord(value []rune, min int, now int64, isBoundary func(rune) bool) (bool, []rune) {
+	for i := range value {
+		if i > 0 && !isBoundary(value[i-1]) {
+			continue
+		}
+
+		cur := n
+		for j := i; j < len(value); j++ {
+			ch, ok := cur.children[value[j]]
+			if !ok {
+				break
+			}
+			cur = ch
+
+			if !ch.live(now) || j-i+1 <= min {
+				continue
+			}
+			if j+1 == len(value) || isBoundary(value[j+1]) {
+				return true, value[i : j+1]
+			}
+		}
+	}
+
+	return false, nil
+}
 
-	first, rest, _ := breakRuneSlice(value)
+// collect calls fn with every word at or below n in lexical order, where
+// sofar is the path from the root to n. It stops as soon as fn returns false.
+func (n *node) collect(sofar []rune, fn func(word []rune) bool) bool {
+	return n.collectTo(sofar, -1, fn)
+}
 
-	ch, ok := n.children[first]
-	if !ok {
+// collectTo works like collect but doesn't descend into words longer than
+// max runes. A negative max has no limit.
+func (n *node) collectTo(sofar []rune, max int, fn func(word []rune) bool) bool {
+	if n.isTerminated && !fn(sofar) {
 		return false
 	}
-	if len(rest) == 0 {
-		if ch.isTerminated {
-			return true
+
+	if max >= 0 && len(sofar) >= max {
+		return true
+	}
+
+	for _, r := range n.sortedKeys() {
+		if !n.children[r].collectTo(append(sofar, r), max, fn) {
+			return false
 		}
-		return false
 	}
-	return ch.isChild(rest)
 
+	return true
+}
+
+// sortedKeys returns the runes of the children of n in order
+func (n *node) sortedKeys() []rune {
+	keys := make([]rune, 0, len(n.children))
+	for r := range n.children {
+		keys = append(keys, r)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
 }
 
-func (n *node) isChildWithDepth(value []rune, depth int, sofar []rune) (bool, []rune) {
-	first, rest, _ := breakRuneSlice(value)
-	sofar = append(sofar, first)
+// compact returns a copy of n and everything below it under parent, leaving
+// out branches without words. It returns nil if there are no words at or
+// below n.
+func (n *node) compact(parent *node) *node {
+	c := &node{}
+	*c = *n
+	c.parent = parent
+	c.children = make(map[rune]*node, len(n.children))
+
+	for r, ch := range n.children {
+		if cc := ch.compact(c); cc != nil {
+			c.children[r] = cc
+		}
+	}
 
-	ch, ok := n.children[first]
-	if !ok {
-		return false, sofar
+	if !c.isTerminated && len(c.children) == 0 {
+		return nil
 	}
 
+	return c
+}
+
+// atDepth calls fn with each node depth runes below n, along with the path to
+// it, where sofar is the path from the root to n.
+func (n *node) atDepth(sofar []rune, depth int, fn func(n *node, path []rune)) {
 	if depth == 0 {
-		if ch.isTerminated {
-			return true, sofar
+		fn(n, sofar)
+		return
+	}
+
+	for r, ch := range n.children {
+		ch.atDepth(append(sofar, r), depth-1, fn)
+	}
+}
+
+// terminated calls fn with each node at or below n that ends a word, along
+// with the word, where sofar is the path from the root to n.
+func (n *node) terminated(sofar []rune, fn func(n *node, word []rune)) {
+	if n.isTerminated {
+		fn(n, sofar)
+	}
+
+	for r, ch := range n.children {
+		ch.terminated(append(sofar, r), fn)
+	}
+}
+
+// walkNodes calls fn with each node below n in pre-order, where sofar is the
+// path from the root to n. It returns false if fn stopped the walk.
+func (n *node) walkNodes(sofar []rune, fn func(n *node, path []rune) bool) bool {
+	for _, r := range n.sortedKeys() {
+		ch := n.children[r]
+		path := append(sofar, r)
+		if !fn(ch, path) || !ch.walkNodes(path, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// branching adds n and every node below it to hist by number of children
+func (n *node) branching(hist map[int]int) {
+	hist[len(n.children)]++
+	for _, ch := range n.children {
+		ch.branching(hist)
+	}
+}
+
+// nodeCount returns the number of nodes at or below n
+func (n *node) nodeCount() int {
+	count := 1
+	for _, ch := range n.children {
+		count += ch.nodeCount()
+	}
+	return count
+}
+
+// shrinkMaps copies the children maps of n and every node below it into maps
+// sized to fit.
+func (n *node) shrinkMaps() {
+	children := make(map[rune]*node, len(n.children))
+	for r, ch := range n.children {
+		ch.shrinkMaps()
+		children[r] = ch
+	}
+	n.children = children
+}
+
+// totalRunes sums the lengths of the words at or below n, where depth is how
+// far n is from the root.
+func (n *node) totalRunes(depth int) int {
+	total := 0
+	if n.isTerminated {
+		total += depth
+	}
+
+	for _, ch := range n.children {
+		total += ch.totalRunes(depth + 1)
+	}
+
+	return total
+}
+
+// runeFrequency adds the runes at or below n to freq, and returns the number
+// of words at or below n.
+func (n *node) runeFrequency(freq map[rune]int) int {
+	words := 0
+	if n.isTerminated {
+		words++
+	}
+
+	for _, ch := range n.children {
+		words += ch.runeFrequency(freq)
+	}
+
+	if n.parent != nil {
+		freq[n.value] += words
+	}
+
+	return words
+}
+
+// validate checks n and everything below it, returning the number of words
+// and occurrences it found.
+func (n *node) validate() (int, int, error) {
+	count, total := 0, 0
+
+	if n.isTerminated {
+		count++
+		total += n.occurrences
+	}
+
+	if n.parent != nil && !n.isTerminated && len(n.children) == 0 {
+		return 0, 0, fmt.Errorf("node %q leads to no words", n.value)
+	}
+
+	for r, ch := range n.children {
+		if ch.parent != n {
+			return 0, 0, fmt.Errorf("node %q has the wrong parent", r)
+		}
+		if ch.value != r {
+			return 0, 0, fmt.Errorf("node %q is stored under %q", ch.value, r)
+		}
+
+		c, tot, err := ch.validate()
+		if err != nil {
+			return 0, 0, err
+		}
+		count += c
+		total += tot
+	}
+
+	return count, total, nil
+}
+
+// counts returns the number of words at or below n and the sum of their
+// occurrences.
+func (n *node) counts() (int, int) {
+	count, total := 0, 0
+	if n.isTerminated {
+		count++
+		total += n.occurrences
+	}
+
+	for _, ch := range n.children {
+		c, tot := ch.counts()
+		count += c
+		total += tot
+	}
+
+	return count, total
+}
+
+// maxDepth returns how many runes below n the deepest word is, or 0 if there
+// are none. Nodes left behind by DeleteNoPrune don't count.
+func (n *node) maxDepth() int {
+	max := 0
+	for _, ch := range n.children {
+		d := ch.maxDepth()
+		if d == 0 && !ch.isTerminated {
+			continue
+		}
+		if d+1 > max {
+			max = d + 1
+		}
+	}
+	return max
+}
+
+// removeWhere unsets every word at or below n for which remove returns true,
+// where sofar is the path from the root to n, pruning branches left without
+// words. It returns the number of words and occurrences taken out.
+func (n *node) removeWhere(sofar []rune, remove func(n *node, word []rune) bool) (int, int) {
+	count, occurrences := 0, 0
+
+	for r, ch := range n.children {
+		c, o := ch.removeWhere(append(sofar, r), remove)
+		count += c
+		occurrences += o
+
+		if !ch.isTerminated && len(ch.children) == 0 {
+			delete(n.children, r)
+		}
+	}
+
+	if n.isTerminated && remove(n, sofar) {
+		count++
+		occurrences += n.occurrences
+		n.isTerminated = false
+		n.occurrences = 0
+		n.expires = 0
+	}
+
+	return count, occurrences
+}
+
+// hasWords determines if there is a word at or below n. It is safe to call on
+// a nil node. Branches are normally pruned once they have no words, so the
+// first child tried nearly always leads straight to one, but those left by
+// DeleteNoPrune are skipped over.
+func (n *node) hasWords() bool {
+	if n == nil {
+		return false
+	}
+	if n.isTerminated {
+		return true
+	}
+
+	for _, ch := range n.children {
+		if ch.hasWords() {
+			return true
+		}
+	}
+	return false
+}
+
+// walk follows value down from n and returns the node it ends on, or nil if
+// the path isn't in the trie.
+func (n *node) walk(value []rune) *node {
+	for _, r := range value {
+		ch, ok := n.children[r]
+		if !ok {
+			return nil
 		}
+		n = ch
 	}
+	return n
+}
 
-	if depth != 0 {
-		depth--
+func (n *node) isChild(value []rune, now int64) bool {
+	if len(value) == 0 {
+		return false
 	}
 
-	return ch.isChildWithDepth(rest, depth, sofar)
+	ch := n.walk(value)
+	return ch != nil && ch.live(now)
+}
+
+// isChildWithDepth looks for a word at the start of value that is longer than
+// depth runes, and returns the part of value that it covers. It loops rather
+// than recursing so that long inputs can't run the stack up. At most steps
+// nodes are visited, and what is left of steps is returned; a negative steps
+// is never used up.
+func (n *node) isChildWithDepth(value []rune, depth int, now int64, steps int) (bool, []rune, int) {
+	for i, r := range value {
+		if steps == 0 {
+			return false, nil, 0
+		}
+		steps--
+
+		ch, ok := n.children[r]
+		if !ok {
+			return false, nil, steps
+		}
+
+		if i >= depth && ch.live(now) {
+			return true, value[:i+1], steps
+		}
+
+		n = ch
+	}
 
+	return false, nil, steps
 }
@@ -15,8 +15,15 @@
 package trie
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"unicode"
 )
 
 func TestTrieLoading(t *testing.T) {
@@ -82,6 +89,24 @@ func TestTrieDelete(t *testing.T) {
 		}
 	}
 
+	for _, absent := range []string{"copper", "co", "space"} {
+		if err := trie.Delete(absent); !errors.Is(err, ErrNotFound) {
+			t.Errorf("For %s Expected %v, got %v", absent, ErrNotFound, err)
+		}
+	}
+
+	if trie.Count() != len(list)-1 {
+		t.Errorf("Expected %d, got %d", len(list)-1, trie.Count())
+	}
+
+	if err := trie.Delete("copperhead"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if got := trie.NextRunes("cop"); !reflect.DeepEqual(got, []rune{'y'}) {
+		t.Errorf("Expected deleted branch to be pruned, got %q", got)
+	}
+
 }
 
 func TestTrieLoadingEmpty(t *testing.T) {
@@ -122,6 +147,157 @@ func TestTrieLoadingBadFile(t *testing.T) {
 	}
 }
 
+func TestTrieLoadSorted(t *testing.T) {
+
+	cases := []struct {
+		In  []string
+		Err error
+	}{
+		{[]string{"COP", "Copper", "copy", "copy", "work", "workshop"}, nil},
+		{[]string{"cop", "copy", "copper"}, ErrUnsorted},
+		{[]string{"b", "a"}, ErrUnsorted},
+		{[]string{}, ErrTrieLoadEmpty},
+	}
+
+	for _, c := range cases {
+		sorted := New()
+		err := sorted.LoadSorted(c.In)
+		if !errors.Is(err, c.Err) {
+			t.Errorf("For %v Expected %v, got %v", c.In, c.Err, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		loaded := New()
+		if err := loaded.Load(c.In); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+
+		if !reflect.DeepEqual(loaded.Words(), sorted.Words()) {
+			t.Errorf("For %v Expected %v, got %v", c.In, loaded.Words(), sorted.Words())
+		}
+		if loaded.Count() != sorted.Count() || loaded.Total() != sorted.Total() || loaded.MaxWordLen() != sorted.MaxWordLen() {
+			t.Errorf("For %v Expected counts to match Load", c.In)
+		}
+		if err := sorted.validate(); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+	}
+
+	// words already in the trie don't need to fit the order
+	trie := New()
+	if err := trie.Add("zebra"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.LoadSorted([]string{"apple", "zebra"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if trie.Count() != 2 || trie.Occurrences("zebra") != 2 {
+		t.Errorf("Expected %d words, got %d", 2, trie.Count())
+	}
+
+}
+
+func TestTrieLoadJSON(t *testing.T) {
+
+	cases := []struct {
+		In    string
+		Count int
+		Err   string
+	}{
+		{`["copy", "copper", "Copy"]`, 2, ""},
+		{`["work"]`, 1, ""},
+		{`[]`, 0, "cannot load empty"},
+		{`[{"value":"copy"}]`, 0, "cannot unmarshall"},
+		{`["copy",`, 0, "cannot unmarshall"},
+		{``, 0, "cannot unmarshall"},
+	}
+
+	for _, c := range cases {
+		trie := New()
+
+		err := trie.LoadJSON([]byte(c.In))
+		if c.Err == "" && err != nil {
+			t.Errorf("For %s Expected no error, got %v", c.In, err)
+		}
+		if c.Err != "" && (err == nil || !strings.Contains(err.Error(), c.Err)) {
+			t.Errorf("For %s Expected %q error, got %v", c.In, c.Err, err)
+		}
+
+		if trie.Count() != c.Count {
+			t.Errorf("For %s Expected %d, got %d", c.In, c.Count, trie.Count())
+		}
+	}
+
+}
+
+func TestTrieLoadingGzipFile(t *testing.T) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.json.gz"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if trie.Count() != 6 {
+		t.Errorf("Expected %d, got %d", 6, trie.Count())
+	}
+
+	if !trie.Find("workbench") {
+		t.Errorf("Expected to find workbench in gzipped dictionary")
+	}
+}
+
+func TestTrieLoadingBadGzipFile(t *testing.T) {
+	trie := New()
+
+	err := trie.LoadFile("dict.bad.json.gz")
+	if err == nil || strings.Index(err.Error(), "cannot decompress") < 0 {
+		t.Errorf("Expected 'cannot decompress' error, got %v", err)
+	}
+}
+
+func TestTrieLoadFileStream(t *testing.T) {
+
+	cases := []struct {
+		In    string
+		Count int
+		Err   string
+	}{
+		{"dict.json", 6, ""},
+		{"dict.json.gz", 6, ""},
+		{"dict.full.json", 178694, ""},
+		{"dict_does_not_exists.json", 0, "no such file"},
+		{"dict.bad.json", 0, "cannot unmarshall"},
+		{"dict.bad.json.gz", 0, "cannot decompress"},
+	}
+
+	for _, c := range cases {
+		trie := New()
+
+		err := trie.LoadFileStream(c.In)
+		if c.Err == "" && err != nil {
+			t.Errorf("For %s Expected no error, got %v", c.In, err)
+		}
+		if c.Err != "" && (err == nil || !strings.Contains(err.Error(), c.Err)) {
+			t.Errorf("For %s Expected %q error, got %v", c.In, c.Err, err)
+		}
+
+		if c.Err == "" && trie.Count() != c.Count {
+			t.Errorf("For %s Expected %d, got %d", c.In, c.Count, trie.Count())
+		}
+	}
+
+	trie := New()
+	if err := trie.LoadFileStream("dict.json"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if !trie.Find("workbench") {
+		t.Errorf("Expected to find workbench in streamed dictionary")
+	}
+
+}
+
 func TestTrieFinding(t *testing.T) {
 
 	list := []string{"copy", "copper", "workflow", "workshop", "workbench", "work", "a", "Apple", "appleseed"}
@@ -166,6 +342,236 @@ func TestTrieFinding(t *testing.T) {
 
 }
 
+func TestTrieRunes(t *testing.T) {
+
+	cases := []struct {
+		Name string
+		Opts []Option
+		Add  string
+		Find string
+		Out  bool
+	}{
+		{"default", nil, "Copy", "COPY", true},
+		{"default miss", nil, "copy", "cop", false},
+		{"folder", []Option{WithFolder(strings.ToUpper)}, "copy", "Copy", true},
+		{"reverse", []Option{WithReverse()}, "Work", "work", true},
+		{"trim", []Option{WithTrimSpace()}, " copy\t", "copy ", true},
+		{"no trim", nil, " copy", "copy", false},
+		{"equivalence", []Option{WithRuneEquivalence(map[rune]rune{'0': 'o'})}, "c0py", "copy", true},
+	}
+
+	for _, c := range cases {
+		trie := New(c.Opts...)
+		add, find := []rune(c.Add), []rune(c.Find)
+
+		if err := trie.AddRunes(add); err != nil {
+			t.Errorf("For %s Expected no error, got %s", c.Name, err)
+		}
+		if string(add) != c.Add || string(find) != c.Find {
+			t.Errorf("For %s Expected the input runes to be left alone", c.Name)
+		}
+
+		if got := trie.FindRunes(find); c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.Name, c.Out, got)
+		}
+		if got := trie.Find(c.Find); c.Out != got {
+			t.Errorf("For %s Find Expected %t, got %t", c.Name, c.Out, got)
+		}
+
+		err := trie.DeleteRunes(find)
+		if c.Out && err != nil {
+			t.Errorf("For %s Expected no error, got %s", c.Name, err)
+		}
+		if !c.Out && !errors.Is(err, ErrNotFound) {
+			t.Errorf("For %s Expected %s, got %v", c.Name, ErrNotFound, err)
+		}
+		if c.Out && trie.Count() != 0 {
+			t.Errorf("For %s Expected %d, got %d", c.Name, 0, trie.Count())
+		}
+	}
+
+	trie := New(WithValidateUTF8())
+	if err := trie.AddRunes([]rune{'a', 0xD800}); !errors.Is(err, ErrInvalidUTF8) {
+		t.Errorf("Expected %s, got %v", ErrInvalidUTF8, err)
+	}
+
+	var empty *Trie
+	if err := empty.AddRunes([]rune("copy")); !errors.Is(err, ErrNilTrie) {
+		t.Errorf("Expected %s, got %v", ErrNilTrie, err)
+	}
+
+}
+
+func TestTrieContains(t *testing.T) {
+
+	list := []string{"copy", "copper", "Work"}
+
+	cases := []struct {
+		In  string
+		Out bool
+	}{
+		{"copy", true},
+		{"COPPER", true},
+		{"work", true},
+		{"cop", false},
+		{"copperhead", false},
+		{"", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.Contains(c.In); c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieFindInto(t *testing.T) {
+
+	list := []string{"copy", "copper", "workflow", "Café"}
+
+	cases := []struct {
+		In  string
+		Out bool
+	}{
+		{"copy", true},
+		{"COPPER", true},
+		{"workflow", true},
+		{"café", true},
+		{"cop", false},
+		{"workflows", false},
+		{"", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	// too short for most of the words, so it has to be grown
+	buf := make([]rune, 0, 4)
+
+	for _, c := range cases {
+		if got := trie.FindInto(c.In, buf); c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+		if got := trie.FindInto(c.In, nil); c.Out != got {
+			t.Errorf("For %s with nil buffer Expected %t, got %t", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieFindBatch(t *testing.T) {
+
+	list := []string{"copy", "copper", "workflow", "Café"}
+
+	cases := []struct {
+		In  []string
+		Out []bool
+	}{
+		{[]string{"copy", "cop", "COPPER"}, []bool{true, false, true}},
+		{[]string{"workflow", "workflows", "café", "copy"}, []bool{true, false, true, true}},
+		{[]string{""}, []bool{false}},
+		{[]string{}, []bool{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.FindBatch(c.In); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %v Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+	var empty *Trie
+	if got := empty.FindBatch([]string{"copy"}); !reflect.DeepEqual([]bool{false}, got) {
+		t.Errorf("Expected %v, got %v", []bool{false}, got)
+	}
+
+}
+
+func TestTrieFindLen(t *testing.T) {
+
+	list := []string{"copy", "copper", "Café", "a"}
+
+	cases := []struct {
+		In    string
+		Found bool
+		Len   int
+	}{
+		{"copy", true, 4},
+		{"COPPER", true, 6},
+		{"café", true, 4},
+		{"a", true, 1},
+		{"cop", false, 0},
+		{"", false, 0},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		found, n := trie.FindLen(c.In)
+		if c.Found != found || c.Len != n {
+			t.Errorf("For %s Expected %t %d, got %t %d", c.In, c.Found, c.Len, found, n)
+		}
+	}
+
+	trimmed := New(WithTrimSpace())
+	if err := trimmed.Add("copy"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if found, n := trimmed.FindLen("  copy "); !found || n != 4 {
+		t.Errorf("Expected %t %d, got %t %d", true, 4, found, n)
+	}
+
+}
+
+func TestTrieFindIntoAllocs(t *testing.T) {
+
+	trie := New()
+
+	if err := trie.Load([]string{"copy", "Copper", "workflow"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	buf := make([]rune, 0, 16)
+	words := []string{"COPY", "CopPer", "WorkFlow", "Café"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, w := range words {
+			trie.FindInto(w, buf)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Expected %d allocations, got %v", 0, allocs)
+	}
+
+	allocs = testing.AllocsPerRun(100, func() {
+		trie.AnyPrefixMatches(words)
+	})
+	// only the results and the shared buffer, however many words there are
+	if allocs > 2 {
+		t.Errorf("Expected at most %d allocations, got %v", 2, allocs)
+	}
+
+}
+
 func TestTrieFindLoadOrderBug(t *testing.T) {
 
 	list := []string{"workbench", "work"}
@@ -289,47 +695,3089 @@ func TestTrieIsContainedSubStringBug(t *testing.T) {
 
 }
 
-func BenchmarkSearch(b *testing.B) {
+func TestTrieCursor(t *testing.T) {
+
+	list := []string{"cop", "copy", "copper"}
+
 	trie := New()
 
-	if err := trie.LoadFile("dict.full.json"); err != nil {
-		b.Errorf("Expected no error, got %v", err)
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
 	}
 
-	data, err := fileToStringSlice("dict.full.json")
+	cur := trie.Cursor()
 
-	if err != nil {
-		b.Fatalf("Error in reading in file for testing %v", err)
+	cases := []struct {
+		In         rune
+		Advanced   bool
+		Terminated bool
+		String     string
+	}{
+		{'c', true, false, "c"},
+		{'O', true, false, "co"},
+		{'x', false, false, "co"},
+		{'p', true, true, "cop"},
+		{'p', true, false, "copp"},
+		{'y', false, false, "copp"},
+		{'e', true, false, "coppe"},
+		{'r', true, true, "copper"},
 	}
 
-	dictloop := 0
-
-	// run the Fib function b.N times
-	for n := 0; n < b.N; n++ {
-		if dictloop >= len(data) {
-			dictloop = 0
+	for _, c := range cases {
+		got := cur.Advance(c.In)
+		if c.Advanced != got {
+			t.Errorf("For %q Expected %t, got %t", c.In, c.Advanced, got)
+		}
+		if c.Terminated != cur.Terminated() {
+			t.Errorf("For %q Expected terminated %t, got %t", c.In, c.Terminated, cur.Terminated())
+		}
+		if c.String != cur.String() {
+			t.Errorf("For %q Expected %q, got %q", c.In, c.String, cur.String())
 		}
-		trie.Find(data[dictloop])
-		dictloop++
 	}
-}
-
-func BenchmarkContains(b *testing.B) {
-	trie := New()
 
-	if err := trie.LoadFile("dict.full.json"); err != nil {
-		b.Errorf("Expected no error, got %v", err)
+	cur.Reset()
+	if cur.Terminated() {
+		t.Errorf("Expected cursor at root to not be terminated")
+	}
+	if cur.String() != "" {
+		t.Errorf("Expected cursor at root to be %q, got %q", "", cur.String())
+	}
+	if !cur.Advance('c') {
+		t.Errorf("Expected cursor to advance from root after Reset")
 	}
 
-	data, err := fileToStringSlice("dict.full.json")
+}
 
-	if err != nil {
-		b.Fatalf("Error in reading in file for testing %v", err)
-	}
+func TestTrieCursorTrimSpace(t *testing.T) {
 
-	dictloop := 0
+	trie := New(WithTrimSpace())
 
-	// run the Fib function b.N times
+	if err := trie.Add("a b"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	cur := trie.Cursor()
+
+	cases := []struct {
+		In       rune
+		Advanced bool
+		String   string
+	}{
+		{'a', true, "a"},
+		{'\t', false, "a"},
+		{' ', true, "a "},
+		{' ', false, "a "},
+		{'B', true, "a b"},
+	}
+
+	for _, c := range cases {
+		if got := cur.Advance(c.In); c.Advanced != got {
+			t.Errorf("For %q Expected %t, got %t", c.In, c.Advanced, got)
+		}
+		if c.String != cur.String() {
+			t.Errorf("For %q Expected %q, got %q", c.In, c.String, cur.String())
+		}
+	}
+
+	if !cur.Terminated() {
+		t.Errorf("Expected cursor to be at the end of %q", "a b")
+	}
+
+	empty := New(WithFolder(func(string) string { return "" }))
+	if empty.Cursor().Advance('a') {
+		t.Errorf("Expected a rune that folds to nothing not to advance")
+	}
+
+}
+
+func TestTrieLoadingDuplicates(t *testing.T) {
+
+	list := []string{"copy", "copper", "copy", "COPY"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if trie.Count() != 2 {
+		t.Errorf("Expected %d, got %d", 2, trie.Count())
+	}
+
+}
+
+func TestTrieLoadingFiles(t *testing.T) {
+	trie := New()
+
+	if err := trie.LoadFiles("dict.json", "dict.json.gz"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	if trie.Count() != 6 {
+		t.Errorf("Expected %d, got %d", 6, trie.Count())
+	}
+
+	err := trie.LoadFiles("dict.json", "dict.bad.json")
+	if err == nil || strings.Index(err.Error(), "dict.bad.json") < 0 {
+		t.Errorf("Expected error naming dict.bad.json, got %v", err)
+	}
+}
+
+func TestTrieOccurrences(t *testing.T) {
+
+	list := []string{"copy", "copper", "copy", "COPY", "work"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	cases := []struct {
+		In  string
+		Out int
+	}{
+		{"copy", 3},
+		{"copper", 1},
+		{"work", 1},
+		{"cop", 0},
+		{"space", 0},
+	}
+
+	for _, c := range cases {
+		got := trie.Occurrences(c.In)
+		if c.Out != got {
+			t.Errorf("For %s Expected %d, got %d", c.In, c.Out, got)
+		}
+	}
+
+	if trie.Count() != 3 {
+		t.Errorf("Expected %d, got %d", 3, trie.Count())
+	}
+	if trie.Total() != len(list) {
+		t.Errorf("Expected %d, got %d", len(list), trie.Total())
+	}
+
+	if err := trie.Delete("copy"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if trie.Total() != 2 {
+		t.Errorf("Expected %d, got %d", 2, trie.Total())
+	}
+	if trie.Occurrences("copy") != 0 {
+		t.Errorf("Expected %d, got %d", 0, trie.Occurrences("copy"))
+	}
+
+}
+
+func TestTrieComplete(t *testing.T) {
+
+	list := []string{"workshop", "copy", "workflow", "work", "copper", "workbench", "works"}
+
+	cases := []struct {
+		In    string
+		Limit int
+		Out   []string
+	}{
+		{"work", 3, []string{"work", "workbench", "workflow"}},
+		{"work", 0, []string{"work", "workbench", "workflow", "works", "workshop"}},
+		{"WorkS", 10, []string{"works", "workshop"}},
+		{"cop", 10, []string{"copper", "copy"}},
+		{"", 2, []string{"copper", "copy"}},
+		{"space", 10, []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := trie.Complete(c.In, c.Limit)
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieShortestPrefix(t *testing.T) {
+
+	list := []string{"a", "apple", "copper", "copperhead", "work"}
+
+	cases := []struct {
+		In     string
+		Report string
+		Out    bool
+	}{
+		{"apples", "a", true},
+		{"Apple", "a", true},
+		{"copperheads", "copper", true},
+		{"copper", "copper", true},
+		{"cop", "", false},
+		{"workshop", "work", true},
+		{"space", "", false},
+		{"", "", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		gotw, got := trie.ShortestPrefix(c.In)
+		if c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+
+		if c.Report != gotw {
+			t.Errorf("For %q Expected %q, got %q", c.In, c.Report, gotw)
+		}
+	}
+
+}
+
+func TestTrieIsExtensionOf(t *testing.T) {
+
+	list := []string{"a", "a.b", "a.b.c.d", "service.api", "service.api.v1"}
+
+	cases := []struct {
+		In     string
+		Report string
+		Out    bool
+	}{
+		{"a.b.c", "a.b", true},
+		{"a.b", "a", true},
+		{"a", "", false},
+		{"a.b.c.d.e", "a.b.c.d", true},
+		{"SERVICE.API.V1", "service.api", true},
+		{"service.api.v2", "service.api", true},
+		{"service.ap", "", false},
+		{"b.a", "", false},
+		{"", "", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got, gotw := trie.IsExtensionOf(c.In)
+		if c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+
+		if c.Report != gotw {
+			t.Errorf("For %q Expected %q, got %q", c.In, c.Report, gotw)
+		}
+	}
+
+}
+
+func TestTrieAnyPrefixMatches(t *testing.T) {
+
+	list := []string{"/api/", "/static/", "/API/v2/admin", "/health"}
+
+	cases := []struct {
+		In  string
+		Out bool
+	}{
+		{"/api/users", true},
+		{"/API/V2/ADMIN/users", true},
+		{"/static/", true},
+		{"/health", true},
+		{"/healthz", true},
+		{"/heal", false},
+		{"/admin", false},
+		{"", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	ins, outs := []string{}, []bool{}
+	for _, c := range cases {
+		ins = append(ins, c.In)
+		outs = append(outs, c.Out)
+	}
+
+	if got := trie.AnyPrefixMatches(ins); !reflect.DeepEqual(outs, got) {
+		t.Errorf("Expected %v, got %v", outs, got)
+	}
+
+	var empty *Trie
+	if got := empty.AnyPrefixMatches(ins); len(got) != len(ins) {
+		t.Errorf("Expected %d results, got %d", len(ins), len(got))
+	}
+
+}
+
+func TestTrieNextRunes(t *testing.T) {
+
+	list := []string{"copy", "copper", "cope", "work", "workbench", "works"}
+
+	cases := []struct {
+		In  string
+		Out []rune
+	}{
+		{"cop", []rune{'e', 'p', 'y'}},
+		{"COP", []rune{'e', 'p', 'y'}},
+		{"work", []rune{'b', 's'}},
+		{"", []rune{'c', 'w'}},
+		{"copy", []rune{}},
+		{"space", []rune{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := trie.NextRunes(c.In)
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s Expected %q, got %q", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieJSON(t *testing.T) {
+
+	list := []string{"copy", "copper", "workflow", "workshop", "workbench", "work", "copy"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	data, err := json.Marshal(trie)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	got := New()
+	if err := json.Unmarshal(data, got); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+
+	if trie.Count() != got.Count() {
+		t.Errorf("Expected %d, got %d", trie.Count(), got.Count())
+	}
+	if trie.Total() != got.Total() {
+		t.Errorf("Expected %d, got %d", trie.Total(), got.Total())
+	}
+	if !reflect.DeepEqual(trie.Complete("", 0), got.Complete("", 0)) {
+		t.Errorf("Expected %v, got %v", trie.Complete("", 0), got.Complete("", 0))
+	}
+
+	again, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if string(data) != string(again) {
+		t.Errorf("Expected %s, got %s", data, again)
+	}
+
+	bad := `{"value":"","terminated":false,"children":{"ab":{"value":"ab","terminated":true,"children":{}}}}`
+	if err := json.Unmarshal([]byte(bad), New()); err == nil {
+		t.Errorf("Expected error for multi rune child, got nil")
+	}
+
+}
+
+func TestTrieWalkPrefix(t *testing.T) {
+
+	list := []string{"a", "apple", "appleseed", "copy"}
+
+	type step struct {
+		Word       string
+		Terminated bool
+	}
+
+	cases := []struct {
+		In  string
+		Out []step
+	}{
+		{"apples", []step{{"a", true}, {"ap", false}, {"app", false}, {"appl", false}, {"apple", true}, {"apples", false}}},
+		{"Cope", []step{{"c", false}, {"co", false}, {"cop", false}}},
+		{"space", []step{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := []step{}
+		trie.WalkPrefix(c.In, func(word string, terminated bool) {
+			got = append(got, step{word, terminated})
+		})
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieLongWord(t *testing.T) {
+
+	long := strings.Repeat("ab", 50000)
+
+	trie := New()
+
+	if err := trie.Add(long); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if !trie.Find(long) {
+		t.Errorf("Expected to find %d rune word", len(long))
+	}
+
+	if trie.Find(long + "a") {
+		t.Errorf("Expected not to find %d rune word", len(long)+1)
+	}
+
+	got, gotw := trie.IsContained("xx"+long+"xx", 3)
+	if !got || gotw != long {
+		t.Errorf("Expected %d rune word to be contained, got %t with %d runes", len(long), got, len(gotw))
+	}
+
+	if err := trie.Delete(long); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if trie.Find(long) {
+		t.Errorf("Expected %d rune word to be deleted", len(long))
+	}
+
+}
+
+func TestTrieIsContainedMultibyte(t *testing.T) {
+
+	list := []string{"café", "naïve", "tea"}
+
+	cases := []struct {
+		In      string
+		Min     int
+		Report  string
+		Out     bool
+		ByteRep string
+		ByteOut bool
+	}{
+		{"un café noir", 3, "café", true, "café", true},
+		{"un café noir", 4, "", false, "café", true},
+		{"un café noir", 5, "", false, "", false},
+		{"so NAÏVE", 5, "", false, "naïve", true},
+		{"green tea", 2, "tea", true, "tea", true},
+		{"green tea", 3, "", false, "", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got, gotw := trie.IsContained(c.In, c.Min)
+		if c.Out != got || c.Report != gotw {
+			t.Errorf("For %s with %d runes Expected %t %q, got %t %q", c.In, c.Min, c.Out, c.Report, got, gotw)
+		}
+
+		got, gotw = trie.IsContainedMinBytes(c.In, c.Min)
+		if c.ByteOut != got || c.ByteRep != gotw {
+			t.Errorf("For %s with %d bytes Expected %t %q, got %t %q", c.In, c.Min, c.ByteOut, c.ByteRep, got, gotw)
+		}
+	}
+
+}
+
+func TestTrieWordsInLengthRange(t *testing.T) {
+
+	list := []string{"a", "cop", "copy", "copper", "work", "workflow", "workbench"}
+
+	cases := []struct {
+		Min int
+		Max int
+		Out []string
+	}{
+		{4, 8, []string{"copper", "copy", "work", "workflow"}},
+		{0, 1, []string{"a"}},
+		{9, 20, []string{"workbench"}},
+		{3, 3, []string{"cop"}},
+		{10, 20, []string{}},
+		{5, 4, []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := trie.WordsInLengthRange(c.Min, c.Max)
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %d-%d Expected %v, got %v", c.Min, c.Max, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieIsContainedNUL(t *testing.T) {
+
+	list := []string{"bad\x00", "a\x00b", "x"}
+
+	cases := []struct {
+		In     string
+		Report string
+		Out    bool
+	}{
+		{"so bad\x00!", "bad\x00", true},
+		{"bad", "", false},
+		{"bad\x00\x00", "bad\x00", true},
+		{"a\x00b", "a\x00b", true},
+		{"a\x00", "", false},
+		{"\x00\x00x", "x", true},
+		{"\x00", "", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got, gotw := trie.IsContained(c.In, 0)
+		if c.Out != got {
+			t.Errorf("For %q Expected %t, got %t", c.In, c.Out, got)
+		}
+		if c.Report != gotw {
+			t.Errorf("For %q Expected %q, got %q", c.In, c.Report, gotw)
+		}
+	}
+
+}
+
+func TestTrieIsContainedFirstRunes(t *testing.T) {
+
+	trie := New()
+
+	if err := trie.Load([]string{"zap", "éclair"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	cases := []struct {
+		Add string
+		In  string
+		Out bool
+	}{
+		{"", "a zap", true},
+		{"", "an éclair", true},
+		{"", "a quiz", false},
+		{"quiz", "a quiz", true},
+		{"Quack", "QUACK!", true},
+	}
+
+	for _, c := range cases {
+		if c.Add != "" {
+			if err := trie.Add(c.Add); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+		}
+		if got, _ := trie.IsContained(c.In, 0); c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+	}
+
+	data, err := json.Marshal(trie)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	restored := New()
+	if _, found := restored.IsContained("zap", 0); found != "" {
+		t.Errorf("Expected empty trie to find nothing, got %s", found)
+	}
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if got, _ := restored.IsContained("a quiz", 0); !got {
+		t.Errorf("For %s Expected %t, got %t", "a quiz", true, got)
+	}
+
+	decoded, err := DecodeTrie(trie.Encode())
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	sub, _ := trie.Subtree("q")
+	rebuilt := []*Trie{decoded, trie.Clone(), sub}
+	trie.Compact()
+	rebuilt = append(rebuilt, trie)
+
+	for i, tr := range rebuilt {
+		if got, _ := tr.IsContained("a quiz", 0); !got {
+			t.Errorf("For rebuilt trie %d Expected %t, got %t", i, true, got)
+		}
+	}
+
+}
+
+// Run with -race: IsContained has to be safe to call from many goroutines
+// at once on a trie that isn't being changed.
+func TestTrieIsContainedConcurrent(t *testing.T) {
+
+	trie := New()
+
+	if err := trie.Load([]string{"zap", "quiz", "éclair"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got, _ := trie.IsContained("a quiz", 0); !got {
+					t.Errorf("For %s Expected %t, got %t", "a quiz", true, got)
+				}
+				if got, _, _ := trie.IsContainedBudget("a quiz", 0, 100); !got {
+					t.Errorf("For %s Expected %t, got %t", "a quiz", true, got)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+}
+
+func TestTrieIsContainedBudget(t *testing.T) {
+
+	cases := []struct {
+		In       string
+		MaxSteps int
+		Out      bool
+		Word     string
+		Err      error
+	}{
+		{"xxcopy", 0, true, "copy", nil},
+		{"xxcopy", 4, true, "copy", nil},
+		{"xxcopy", 3, false, "", ErrStepBudget},
+		{"cococopy", 14, true, "copy", nil},
+		{"cococopy", 13, false, "", ErrStepBudget},
+		{"cocoa", 9, false, "", nil},
+		{"cocoa", 8, false, "", ErrStepBudget},
+	}
+
+	trie := New()
+	if err := trie.Load([]string{"copy", "coconut"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got, word, err := trie.IsContainedBudget(c.In, 1, c.MaxSteps)
+		if !errors.Is(err, c.Err) {
+			t.Errorf("For %s with %d steps Expected error %v, got %v", c.In, c.MaxSteps, c.Err, err)
+		}
+		if got != c.Out || word != c.Word {
+			t.Errorf("For %s with %d steps Expected %t %q, got %t %q", c.In, c.MaxSteps, c.Out, c.Word, got, word)
+		}
+	}
+
+}
+
+func TestTrieIsContainedWholeWord(t *testing.T) {
+
+	list := []string{"ass", "class", "work", "workshop"}
+
+	cases := []struct {
+		In     string
+		Report string
+		Out    bool
+	}{
+		{"class", "class", true},
+		{"subclass", "", false},
+		{"a classic", "", false},
+		{"you ass!", "ass", true},
+		{"Ass-kicking", "ass", true},
+		{"homework", "", false},
+		{"work", "work", true},
+		{"the workshop, today", "workshop", true},
+		{"the workshops", "", false},
+		{"work1", "work", true},
+		{"my_ass_var", "ass", true},
+		{"", "", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got, gotw := trie.IsContainedWholeWord(c.In, 2)
+		if c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+
+		if c.Report != gotw {
+			t.Errorf("For %q Expected %q, got %q", c.In, c.Report, gotw)
+		}
+	}
+
+}
+
+func TestTrieIsContainedWholeWordFunc(t *testing.T) {
+
+	list := []string{"ass", "class", "work", "workshop"}
+
+	isBoundary := func(r rune) bool {
+		return r == '_' || r == '/' || unicode.IsSpace(r)
+	}
+
+	cases := []struct {
+		In     string
+		Report string
+		Out    bool
+	}{
+		{"my_ass_var", "ass", true},
+		{"ASS_MODE", "ass", true},
+		{"src/work/main.go", "work", true},
+		{"work_shop", "work", true},
+		{"workshop_hours", "workshop", true},
+		{"sub_class", "class", true},
+		{"subclass_name", "", false},
+		{"ass-kicking", "", false},
+		{"work1", "", false},
+		{"", "", false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got, gotw := trie.IsContainedWholeWordFunc(c.In, 2, isBoundary)
+		if c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+
+		if c.Report != gotw {
+			t.Errorf("For %q Expected %q, got %q", c.In, c.Report, gotw)
+		}
+	}
+
+	got, gotw := trie.IsContainedWholeWordFunc("work-shop", 2, nil)
+	if !got || gotw != "work" {
+		t.Errorf("For nil boundary Expected %q, got %q", "work", gotw)
+	}
+
+}
+
+func TestTrieCommonPrefix(t *testing.T) {
+
+	cases := []struct {
+		In  []string
+		Out string
+	}{
+		{[]string{"workflow", "workshop", "workbench"}, "work"},
+		{[]string{"workshop", "work"}, "work"},
+		{[]string{"copy"}, "copy"},
+		{[]string{"copy", "work"}, ""},
+		{[]string{}, ""},
+	}
+
+	for _, c := range cases {
+		trie := New()
+
+		for _, v := range c.In {
+			if err := trie.Add(v); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+		}
+
+		got := trie.CommonPrefix()
+		if c.Out != got {
+			t.Errorf("For %v Expected %q, got %q", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieWithFolder(t *testing.T) {
+
+	turkish := func(s string) string {
+		return strings.ToLowerSpecial(unicode.TurkishCase, s)
+	}
+
+	cases := []struct {
+		In      string
+		Default bool
+		Turkish bool
+	}{
+		{"kiz", true, false},
+		{"kız", false, true},
+		{"KIZ", true, true},
+		{"İSTANBUL", true, true},
+		{"ISTANBUL", true, false},
+	}
+
+	list := []string{"KIZ", "İstanbul"}
+
+	def := New()
+	if err := def.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	tr := New(WithFolder(turkish))
+	if err := tr.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := def.Find(c.In); c.Default != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Default, got)
+		}
+		if got := tr.Find(c.In); c.Turkish != got {
+			t.Errorf("For %s with Turkish folding Expected %t, got %t", c.In, c.Turkish, got)
+		}
+	}
+
+	cur := tr.Cursor()
+	if !cur.Advance('K') || !cur.Advance('I') || !cur.Advance('Z') || !cur.Terminated() {
+		t.Errorf("Expected cursor to use Turkish folding")
+	}
+
+}
+
+func TestTrieDeleteMany(t *testing.T) {
+
+	list := []string{"cop", "copy", "copper", "copperhead", "work"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	got := trie.DeleteMany("copy", "space", "work", "copy", "co")
+
+	cases := []struct {
+		In  string
+		Out error
+	}{
+		{"copy", nil},
+		{"space", ErrNotFound},
+		{"work", nil},
+		{"co", ErrNotFound},
+	}
+
+	if len(got) != len(cases) {
+		t.Errorf("Expected %d results, got %d", len(cases), len(got))
+	}
+
+	for _, c := range cases {
+		if c.Out != got[c.In] {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Out, got[c.In])
+		}
+	}
+
+	if trie.Count() != len(list)-2 {
+		t.Errorf("Expected %d, got %d", len(list)-2, trie.Count())
+	}
+
+}
+
+func TestTrieNavigate(t *testing.T) {
+
+	list := []string{"copy", "copper", "workflow", "workshop", "workbench", "work"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if _, ok := trie.Navigate("space"); ok {
+		t.Errorf("Expected no subtree for space")
+	}
+
+	st, ok := trie.Navigate("WORK")
+	if !ok {
+		t.Fatalf("Expected a subtree for work")
+	}
+
+	want := []string{"work", "workbench", "workflow", "workshop"}
+	if got := st.Words(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if st.Count() != len(want) {
+		t.Errorf("Expected %d, got %d", len(want), st.Count())
+	}
+
+	cases := []struct {
+		In     string
+		Trie   bool
+		Scoped bool
+	}{
+		{"", true, true},
+		{"s", false, true},
+		{"sh", false, true},
+		{"work", true, false},
+		{"co", true, false},
+		{"x", false, false},
+	}
+
+	for _, c := range cases {
+		if got := trie.HasPrefix(c.In); c.Trie != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Trie, got)
+		}
+		if got := st.HasPrefix(c.In); c.Scoped != got {
+			t.Errorf("For %s in subtree Expected %t, got %t", c.In, c.Scoped, got)
+		}
+	}
+
+}
+
+func TestTrieWordsDeterministic(t *testing.T) {
+
+	list := []string{"workshop", "copy", "a", "workflow", "work", "copper", "workbench", "zebra", "apple"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	want := []string{"a", "apple", "copper", "copy", "work", "workbench", "workflow", "workshop", "zebra"}
+
+	for i := 0; i < 20; i++ {
+		got := trie.Words()
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("On pass %d Expected %v, got %v", i, want, got)
+		}
+	}
+
+}
+
+func TestTrieUpdatePlan(t *testing.T) {
+
+	list := []string{"copy", "copper", "work", "workshop"}
+
+	cases := []struct {
+		In     []string
+		Add    []string
+		Remove []string
+	}{
+		{[]string{"copy", "copper", "work", "workshop"}, []string{}, []string{}},
+		{[]string{"Work", "zebra", "apple", "ZEBRA", "copy"}, []string{"zebra", "apple"}, []string{"copper", "workshop"}},
+		{[]string{}, []string{}, []string{"copper", "copy", "work", "workshop"}},
+	}
+
+	for _, c := range cases {
+		trie := New()
+
+		if err := trie.Load(list); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+
+		toAdd, toRemove := trie.UpdatePlan(c.In)
+		if !reflect.DeepEqual(c.Add, toAdd) {
+			t.Errorf("For %v Expected to add %v, got %v", c.In, c.Add, toAdd)
+		}
+		if !reflect.DeepEqual(c.Remove, toRemove) {
+			t.Errorf("For %v Expected to remove %v, got %v", c.In, c.Remove, toRemove)
+		}
+
+		for _, w := range toAdd {
+			if err := trie.Add(w); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+		}
+		for _, w := range toRemove {
+			if err := trie.Delete(w); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+		}
+
+		if toAdd, toRemove := trie.UpdatePlan(c.In); len(toAdd) != 0 || len(toRemove) != 0 {
+			t.Errorf("For %v Expected no changes after applying plan, got %v %v", c.In, toAdd, toRemove)
+		}
+	}
+
+}
+
+func TestTrieApply(t *testing.T) {
+
+	list := []string{"copy", "copper", "work", "workshop"}
+
+	cases := []struct {
+		Add     []string
+		Remove  []string
+		Err     error
+		Words   []string
+		Partial []string
+	}{
+		{[]string{"zebra"}, []string{"work"}, nil, []string{"copper", "copy", "workshop", "zebra"}, []string{"copper", "copy", "workshop", "zebra"}},
+		{[]string{"zebra"}, []string{"copy", "missing"}, ErrNotFound, list, []string{"copper", "work", "workshop"}},
+		{[]string{"zebra", "caf\xe9"}, []string{"copy"}, ErrInvalidUTF8, list, []string{"copper", "work", "workshop", "zebra"}},
+	}
+
+	for _, c := range cases {
+		for _, rollback := range []bool{true, false} {
+			trie := New(WithValidateUTF8())
+
+			if err := trie.Load(list); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+
+			var err error
+			want := c.Words
+			if rollback {
+				err = trie.Apply(c.Add, c.Remove)
+			} else {
+				err = trie.ApplyNoRollback(c.Add, c.Remove)
+				want = c.Partial
+			}
+
+			if !errors.Is(err, c.Err) {
+				t.Errorf("For %v %v Expected %v, got %v", c.Add, c.Remove, c.Err, err)
+			}
+
+			sorted := append([]string{}, want...)
+			sort.Strings(sorted)
+			if got := trie.SortedWords(); !reflect.DeepEqual(sorted, got) {
+				t.Errorf("For %v %v rollback %t Expected %v, got %v", c.Add, c.Remove, rollback, sorted, got)
+			}
+
+			if err := trie.validate(); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+		}
+	}
+
+}
+
+func TestTrieClone(t *testing.T) {
+
+	trie := New(WithReverse())
+
+	if err := trie.Load([]string{"walking", "talking", "walking"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	c := trie.Clone()
+
+	if err := c.Add("running"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := c.Delete("talking"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if got := trie.SortedWords(); !reflect.DeepEqual([]string{"talking", "walking"}, got) {
+		t.Errorf("Expected original unchanged, got %v", got)
+	}
+	if got := c.SortedWords(); !reflect.DeepEqual([]string{"running", "walking"}, got) {
+		t.Errorf("Expected %v, got %v", []string{"running", "walking"}, got)
+	}
+	if c.Occurrences("walking") != 2 || !c.HasSuffix("sleepwalking") {
+		t.Errorf("Expected clone to keep occurrences and options")
+	}
+
+	var nilTrie *Trie
+	if nilTrie.Clone() != nil {
+		t.Errorf("Expected clone of nil trie to be nil")
+	}
+
+}
+
+func TestTrieDiff(t *testing.T) {
+
+	before := New()
+	if err := before.Load([]string{"copy", "copper", "work", "workshop"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	after := New()
+	if err := after.Load([]string{"copper", "work", "workflow", "apple"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	added, removed := after.Diff(before)
+
+	if want := []string{"apple", "workflow"}; !reflect.DeepEqual(want, added) {
+		t.Errorf("Expected added %v, got %v", want, added)
+	}
+	if want := []string{"copy", "workshop"}; !reflect.DeepEqual(want, removed) {
+		t.Errorf("Expected removed %v, got %v", want, removed)
+	}
+
+	added, removed = after.Diff(after)
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Expected no differences, got %v and %v", added, removed)
+	}
+
+}
+
+func TestTrieAddPath(t *testing.T) {
+
+	cases := []struct {
+		In  string
+		Out []rune
+	}{
+		{"Copy", []rune{'c', 'o', 'p', 'y'}},
+		{"cop", []rune{'c', 'o', 'p'}},
+		{"café", []rune{'c', 'a', 'f', 'é'}},
+		{"", []rune{}},
+	}
+
+	trie := New()
+
+	for _, c := range cases {
+		got, err := trie.AddPath(c.In)
+		if err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s Expected %q, got %q", c.In, c.Out, got)
+		}
+	}
+
+	if !trie.Find("copy") || !trie.Find("café") {
+		t.Errorf("Expected AddPath to add words")
+	}
+
+}
+
+func TestTrieFindAll(t *testing.T) {
+
+	list := []string{"a", "apple", "spam", "copper", "cop"}
+
+	cases := []struct {
+		In             string
+		Min            int
+		NonOverlapping bool
+		Out            []string
+	}{
+		{"spamspam", 0, true, []string{"spam", "spam"}},
+		{"spamspam", 0, false, []string{"spam", "a", "spam", "a"}},
+		{"an apple", 0, false, []string{"a", "a", "apple"}},
+		{"an apple", 0, true, []string{"a", "apple"}},
+		{"Copperhead", 0, false, []string{"cop", "copper", "a"}},
+		{"Copperhead", 0, true, []string{"copper", "a"}},
+		{"Copperhead", 3, true, []string{"copper"}},
+		{"space", 1, false, []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := trie.FindAll(c.In, c.Min, c.NonOverlapping)
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieCompact(t *testing.T) {
+
+	list := []string{"copy", "copper", "copperhead", "workflow", "workshop", "workbench", "work", "copy"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, w := range []string{"copperhead", "workshop", "workbench"} {
+		if err := trie.Delete(w); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+	}
+
+	words := trie.Words()
+	count := trie.Count()
+	total := trie.Total()
+
+	trie.Compact()
+
+	if count != trie.Count() {
+		t.Errorf("Expected %d, got %d", count, trie.Count())
+	}
+	if total != trie.Total() {
+		t.Errorf("Expected %d, got %d", total, trie.Total())
+	}
+	if !reflect.DeepEqual(words, trie.Words()) {
+		t.Errorf("Expected %v, got %v", words, trie.Words())
+	}
+	for _, w := range words {
+		if !trie.Find(w) {
+			t.Errorf("Expected to find %s after Compact", w)
+		}
+	}
+	if trie.Occurrences("copy") != 2 {
+		t.Errorf("Expected %d, got %d", 2, trie.Occurrences("copy"))
+	}
+
+	if err := trie.Add("workshop"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if !trie.Find("workshop") {
+		t.Errorf("Expected to find workshop added after Compact")
+	}
+
+	empty := New()
+	empty.Compact()
+	if empty.Count() != 0 || len(empty.Words()) != 0 {
+		t.Errorf("Expected empty trie to stay empty after Compact")
+	}
+
+}
+
+func TestTrieShrinkMaps(t *testing.T) {
+
+	trie := New()
+
+	for i := 0; i < 10000; i++ {
+		if err := trie.Add(fmt.Sprintf("work%c", rune(0x4e00+i))); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+	}
+	for i := 3; i < 10000; i++ {
+		if err := trie.Delete(fmt.Sprintf("work%c", rune(0x4e00+i))); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+	}
+	if err := trie.Add("copy"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	cursor := trie.Cursor()
+	for _, r := range "wor" {
+		cursor.Advance(r)
+	}
+
+	words := trie.Words()
+
+	// the map under "work" still has room for 10000 children, which takes
+	// well over 100KB. Go doesn't report how much room a map has, so an
+	// estimate from the trie itself couldn't see the difference; the heap is
+	// measured instead, after a collection on each side.
+	before := heapInUse()
+	trie.ShrinkMaps()
+	freed := int64(before) - int64(heapInUse())
+	if freed < 100000 {
+		t.Errorf("Expected ShrinkMaps to free at least %d bytes, got %d", 100000, freed)
+	}
+	t.Logf("ShrinkMaps freed %d bytes", freed)
+
+	if !reflect.DeepEqual(words, trie.Words()) {
+		t.Errorf("Expected %v, got %v", words, trie.Words())
+	}
+	if trie.Count() != 4 {
+		t.Errorf("Expected %d, got %d", 4, trie.Count())
+	}
+	if !cursor.Advance('k') || !cursor.Advance(0x4e01) || !cursor.Terminated() {
+		t.Errorf("Expected cursor to reach a word after ShrinkMaps")
+	}
+	if err := trie.Add("workshop"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if !trie.Find("workshop") {
+		t.Errorf("Expected to find workshop added after ShrinkMaps")
+	}
+
+	var empty *Trie
+	empty.ShrinkMaps()
+
+}
+
+func TestTrieLoadWithProgress(t *testing.T) {
+
+	cases := []struct {
+		Size int
+		Out  []int
+	}{
+		{2500, []int{1000, 2000, 2500}},
+		{2000, []int{1000, 2000}},
+		{3, []int{3}},
+	}
+
+	for _, c := range cases {
+		list := make([]string, c.Size)
+		for i := range list {
+			list[i] = fmt.Sprintf("word%d", i)
+		}
+
+		trie := New()
+
+		got := []int{}
+		err := trie.LoadWithProgress(list, func(done, total int) {
+			if total != c.Size {
+				t.Errorf("Expected total %d, got %d", c.Size, total)
+			}
+			got = append(got, done)
+		})
+		if err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %d Expected %v, got %v", c.Size, c.Out, got)
+		}
+		if trie.Count() != c.Size {
+			t.Errorf("Expected %d, got %d", c.Size, trie.Count())
+		}
+	}
+
+	trie := New()
+	if err := trie.LoadWithProgress([]string{}, nil); err != ErrTrieLoadEmpty {
+		t.Errorf("Expected %v, got %v", ErrTrieLoadEmpty, err)
+	}
+
+}
+
+func TestTrieTotalRunes(t *testing.T) {
+
+	cases := []struct {
+		In    []string
+		Out   int
+		Nodes int
+	}{
+		{[]string{"copy", "copper", "work"}, 14, 11},
+		{[]string{"cop", "copy", "copy"}, 7, 4},
+		{[]string{"café", "naïve"}, 9, 9},
+		{[]string{}, 0, 0},
+	}
+
+	for _, c := range cases {
+		trie := New()
+
+		for _, v := range c.In {
+			if err := trie.Add(v); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+		}
+
+		if got := trie.TotalRunes(); c.Out != got {
+			t.Errorf("For %v Expected %d, got %d", c.In, c.Out, got)
+		}
+
+		if got := trie.NodeCount(); c.Nodes != got {
+			t.Errorf("For %v Expected %d nodes, got %d", c.In, c.Nodes, got)
+		}
+	}
+
+}
+
+func TestTrieBranchingHistogram(t *testing.T) {
+
+	cases := []struct {
+		In  []string
+		Out map[int]int
+	}{
+		{[]string{"copy", "copper", "work"}, map[int]int{0: 3, 1: 7, 2: 2}},
+		{[]string{"a", "b", "c"}, map[int]int{0: 3, 3: 1}},
+		{[]string{}, map[int]int{0: 1}},
+	}
+
+	for _, c := range cases {
+		trie := New()
+
+		for _, v := range c.In {
+			if err := trie.Add(v); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+		}
+
+		if got := trie.BranchingHistogram(); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %v Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieWithReverse(t *testing.T) {
+
+	list := []string{".co.uk", ".uk", ".com", "example.com"}
+
+	cases := []struct {
+		In     string
+		Find   bool
+		Suffix bool
+	}{
+		{"bbc.co.uk", false, true},
+		{"bbc.CO.UK", false, true},
+		{".co.uk", true, true},
+		{"google.com", false, true},
+		{"example.com", true, true},
+		{"example.org", false, false},
+		{"uk", false, false},
+	}
+
+	rev := New(WithReverse())
+	if err := rev.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	fwd := New()
+	if err := fwd.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := rev.Find(c.In); c.Find != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Find, got)
+		}
+		if got := rev.HasSuffix(c.In); c.Suffix != got {
+			t.Errorf("For %s Expected suffix %t, got %t", c.In, c.Suffix, got)
+		}
+		if got := fwd.HasSuffix(c.In); c.Suffix != got {
+			t.Errorf("For %s without reverse Expected suffix %t, got %t", c.In, c.Suffix, got)
+		}
+	}
+
+	if got := rev.Words(); !reflect.DeepEqual([]string{".uk", ".co.uk", ".com", "example.com"}, got) {
+		t.Errorf("Expected words unreversed, got %v", got)
+	}
+
+	if got := rev.Complete(".uk", 0); !reflect.DeepEqual([]string{".uk", ".co.uk"}, got) {
+		t.Errorf("Expected words ending in .uk, got %v", got)
+	}
+
+	got, gotw := rev.IsContained("visit www.example.org/path", 3)
+	if got {
+		t.Errorf("Expected %t, got %t %q", false, got, gotw)
+	}
+
+	got, gotw = rev.IsContained("visit www.example.com/path", 3)
+	if !got || gotw != ".com" {
+		t.Errorf("Expected %t %q, got %t %q", true, ".com", got, gotw)
+	}
+
+}
+
+func TestTrieWordsWithSuffix(t *testing.T) {
+
+	list := []string{"walking", "talking", "king", "ring", "sing", "walked", "Ping"}
+
+	cases := []struct {
+		In      string
+		Forward []string
+		Reverse []string
+	}{
+		{"king", []string{"king", "talking", "walking"}, []string{"king", "talking", "walking"}},
+		{"ING", []string{"king", "ping", "ring", "sing", "talking", "walking"}, []string{"king", "talking", "walking", "ping", "ring", "sing"}},
+		{"ed", []string{"walked"}, []string{"walked"}},
+		{"xyz", []string{}, []string{}},
+	}
+
+	fwd := New()
+	if err := fwd.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	rev := New(WithReverse())
+	if err := rev.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := fwd.WordsWithSuffix(c.In); !reflect.DeepEqual(c.Forward, got) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Forward, got)
+		}
+		if got := rev.WordsWithSuffix(c.In); !reflect.DeepEqual(c.Reverse, got) {
+			t.Errorf("For %s reverse Expected %v, got %v", c.In, c.Reverse, got)
+		}
+	}
+
+	if got := fwd.WordsWithSuffix(""); len(got) != fwd.Count() {
+		t.Errorf("Expected all %d words, got %v", fwd.Count(), got)
+	}
+
+}
+
+func TestTrieWordsContaining(t *testing.T) {
+
+	list := []string{"copper", "copperhead", "hopper", "workshop", "shopping", "Cop"}
+
+	cases := []struct {
+		In  string
+		Out []string
+	}{
+		{"opp", []string{"copper", "copperhead", "hopper", "shopping"}},
+		{"SHOP", []string{"shopping", "workshop"}},
+		{"head", []string{"copperhead"}},
+		{"cop", []string{"cop", "copper", "copperhead"}},
+		{"xyz", []string{}},
+	}
+
+	for _, opts := range [][]Option{{}, {WithReverse()}} {
+		trie := New(opts...)
+
+		if err := trie.Load(list); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+
+		for _, c := range cases {
+			got := trie.WordsContaining(c.In)
+			sort.Strings(got)
+			if !reflect.DeepEqual(c.Out, got) {
+				t.Errorf("For %s Expected %v, got %v", c.In, c.Out, got)
+			}
+		}
+	}
+
+}
+
+func TestTrieRuneFrequency(t *testing.T) {
+
+	list := []string{"cop", "copy", "yo", "copy"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	want := map[rune]int{'c': 2, 'o': 3, 'p': 2, 'y': 2}
+	if got := trie.RuneFrequency(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if got := New().RuneFrequency(); len(got) != 0 {
+		t.Errorf("Expected empty map, got %v", got)
+	}
+
+}
+
+func TestTrieLoadChan(t *testing.T) {
+
+	ch := make(chan string)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ch <- fmt.Sprintf("word%d-%d", i, j)
+			}
+		}(i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+
+	trie := New()
+
+	if err := trie.LoadChan(ch); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if trie.Count() != 400 {
+		t.Errorf("Expected %d, got %d", 400, trie.Count())
+	}
+
+	if !trie.Find("word3-99") {
+		t.Errorf("Expected to find word3-99")
+	}
+
+}
+
+func TestTrieIsEmpty(t *testing.T) {
+
+	trie := New()
+
+	if !trie.IsEmpty() {
+		t.Errorf("Expected new trie to be empty")
+	}
+
+	if err := trie.Load([]string{"copy", "copy", "copper"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if trie.IsEmpty() {
+		t.Errorf("Expected trie with words to not be empty")
+	}
+
+	trie.DeleteMany("copy", "cop", "copper", "copy")
+
+	if !trie.IsEmpty() {
+		t.Errorf("Expected trie to be empty after deleting every word")
+	}
+
+}
+
+func TestTrieIntersection(t *testing.T) {
+
+	first := New()
+	if err := first.Load([]string{"copy", "copper", "work", "workshop", "apple"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	second := New()
+	if err := second.Load([]string{"copper", "work", "workflow"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	third := New()
+	if err := third.Load([]string{"space"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	cases := []struct {
+		A   *Trie
+		B   *Trie
+		Out []string
+	}{
+		{first, second, []string{"copper", "work"}},
+		{second, first, []string{"copper", "work"}},
+		{first, third, []string{}},
+		{first, New(), []string{}},
+	}
+
+	for i, c := range cases {
+		got := c.A.Intersection(c.B)
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For case %d Expected %v, got %v", i, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieAddStream(t *testing.T) {
+
+	trie := New()
+
+	got, err := trie.AddStream(strings.NewReader("copy\n  copper \n\n\t\nwork\r\n"))
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if got != 3 {
+		t.Errorf("Expected %d, got %d", 3, got)
+	}
+
+	got, err = trie.AddStream(strings.NewReader("workshop\ncopy"))
+	if err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if got != 2 {
+		t.Errorf("Expected %d, got %d", 2, got)
+	}
+
+	if trie.Count() != 4 {
+		t.Errorf("Expected %d, got %d", 4, trie.Count())
+	}
+
+	for _, w := range []string{"copy", "copper", "work", "workshop"} {
+		if !trie.Find(w) {
+			t.Errorf("Expected to find %s", w)
+		}
+	}
+
+}
+
+func TestTrieAddTokens(t *testing.T) {
+
+	cases := []struct {
+		In    string
+		Sep   string
+		Added int
+		Words []string
+	}{
+		{"copy the  copper\twork\n", "", 4, []string{"copper", "copy", "the", "work"}},
+		{"copy,,copper,", ",", 2, []string{"copper", "copy"}},
+		{"work::workshop", "::", 2, []string{"work", "workshop"}},
+		{"copy copy", " ", 2, []string{"copy"}},
+		{"   ", "", 0, []string{}},
+	}
+
+	for _, c := range cases {
+		trie := New()
+
+		added, err := trie.AddTokens(c.In, c.Sep)
+		if err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+		if added != c.Added {
+			t.Errorf("For %q Expected %d, got %d", c.In, c.Added, added)
+		}
+		if got := trie.SortedWords(); !reflect.DeepEqual(c.Words, got) {
+			t.Errorf("For %q Expected %v, got %v", c.In, c.Words, got)
+		}
+	}
+
+	var nilTrie *Trie
+	if _, err := nilTrie.AddTokens("copy", ""); !errors.Is(err, ErrNilTrie) {
+		t.Errorf("Expected %s, got %v", ErrNilTrie, err)
+	}
+
+}
+
+func TestTrieWordsByInsertion(t *testing.T) {
+
+	list := []string{"work", "copy", "apple", "Copy", "copper", "zebra"}
+
+	trie := New(WithInsertionOrder())
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	want := []string{"work", "copy", "apple", "copper", "zebra"}
+	if got := trie.WordsByInsertion(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if err := trie.Delete("copy"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Add("copy"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	trie.Compact()
+
+	want = []string{"work", "apple", "copper", "zebra", "copy"}
+	if got := trie.WordsByInsertion(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	plain := New()
+	if err := plain.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	want = []string{"apple", "copper", "copy", "work", "zebra"}
+	if got := plain.WordsByInsertion(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+}
+
+func TestTriePrefixCounts(t *testing.T) {
+
+	list := []string{"a", "cop", "copy", "copper", "Cat", "car", "work", "workshop", "copy"}
+
+	cases := []struct {
+		In  int
+		Out map[string]int
+	}{
+		{1, map[string]int{"a": 1, "c": 5, "w": 2}},
+		{2, map[string]int{"co": 3, "ca": 2, "wo": 2}},
+		{4, map[string]int{"copy": 1, "copp": 1, "work": 2}},
+		{9, map[string]int{}},
+		{0, map[string]int{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.PrefixCounts(c.In); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %d Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieSubtree(t *testing.T) {
+
+	list := []string{"work", "workshop", "Workbench", "workflow", "copy", "copper", "workshop"}
+
+	cases := []struct {
+		In    string
+		Words []string
+		Empty bool
+		OK    bool
+	}{
+		{"work", []string{"bench", "flow", "shop"}, true, true},
+		{"WORKS", []string{"hop"}, false, true},
+		{"cop", []string{"per", "y"}, false, true},
+		{"workshop", []string{}, true, true},
+		{"wor", []string{"k", "kbench", "kflow", "kshop"}, false, true},
+		{"zebra", nil, false, false},
+		{"workshops", nil, false, false},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		sub, ok := trie.Subtree(c.In)
+		if c.OK != ok {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.OK, ok)
+		}
+		if !ok {
+			if sub != nil {
+				t.Errorf("For %s Expected nil trie, got %v", c.In, sub)
+			}
+			continue
+		}
+
+		words := []string{}
+		for _, w := range sub.Words() {
+			if w != "" {
+				words = append(words, w)
+			}
+		}
+		if !reflect.DeepEqual(c.Words, words) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Words, words)
+		}
+		if sub.RootTerminated() != c.Empty {
+			t.Errorf("For %s Expected empty word %t, got %t", c.In, c.Empty, sub.RootTerminated())
+		}
+		want := len(c.Words)
+		if c.Empty {
+			want++
+		}
+		if sub.Count() != want {
+			t.Errorf("For %s Expected count %d, got %d", c.In, want, sub.Count())
+		}
+	}
+
+	sub, _ := trie.Subtree("work")
+	if sub.Occurrences("shop") != 2 {
+		t.Errorf("Expected %d, got %d", 2, sub.Occurrences("shop"))
+	}
+	if err := sub.Add("force"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if trie.Find("workforce") || trie.Find("force") {
+		t.Errorf("Expected changes to the subtree to leave the trie alone")
+	}
+
+	var empty *Trie
+	if sub, ok := empty.Subtree("work"); sub != nil || ok {
+		t.Errorf("Expected nil and false for a nil trie")
+	}
+
+}
+
+func TestTrieWordsOfLength(t *testing.T) {
+
+	list := []string{"a", "cop", "copy", "Cat", "car", "work", "workshop", "copy", "été"}
+
+	cases := []struct {
+		In  int
+		Out []string
+	}{
+		{1, []string{"a"}},
+		{3, []string{"car", "cat", "cop", "été"}},
+		{4, []string{"copy", "work"}},
+		{8, []string{"workshop"}},
+		{5, []string{}},
+		{0, []string{}},
+		{-1, []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.WordsOfLength(c.In); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %d Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieGroupByFirstRune(t *testing.T) {
+
+	list := []string{"copy", "Apple", "cop", "apricot", "zebra", "élan", "copy"}
+
+	want := map[rune][]string{
+		'a': {"apple", "apricot"},
+		'c': {"cop", "copy"},
+		'z': {"zebra"},
+		'é': {"élan"},
+	}
+
+	trie := New()
+
+	if got := trie.GroupByFirstRune(); len(got) != 0 {
+		t.Errorf("Expected no groups, got %v", got)
+	}
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if got := trie.GroupByFirstRune(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+}
+
+func TestTrieRootTerminated(t *testing.T) {
+
+	trie := New(WithTrimSpace())
+
+	if err := trie.Load([]string{"copy", "copper"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if trie.RootTerminated() {
+		t.Errorf("Expected root not to be terminated")
+	}
+
+	if err := trie.Add("  "); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if !trie.RootTerminated() {
+		t.Errorf("Expected root to be terminated")
+	}
+
+	data, err := json.Marshal(trie)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	restored := New()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !restored.RootTerminated() {
+		t.Errorf("Expected restored root to be terminated")
+	}
+
+	if err := trie.Delete(""); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if trie.RootTerminated() {
+		t.Errorf("Expected root not to be terminated after Delete")
+	}
+
+	var nilTrie *Trie
+	if nilTrie.RootTerminated() {
+		t.Errorf("Expected nil trie root not to be terminated")
+	}
+
+}
+
+func TestTrieValidate(t *testing.T) {
+
+	list := []string{"cop", "copy", "copper", "copperhead", "work", "workshop", "copy"}
+
+	trie := New()
+
+	if err := trie.validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.validate(); err != nil {
+		t.Errorf("After Load Expected no error, got %s", err)
+	}
+
+	trie.DeleteMany("copper", "workshop", "space", "co")
+	if err := trie.validate(); err != nil {
+		t.Errorf("After DeleteMany Expected no error, got %s", err)
+	}
+
+	trie.Compact()
+	if err := trie.validate(); err != nil {
+		t.Errorf("After Compact Expected no error, got %s", err)
+	}
+
+	data, err := json.Marshal(trie)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if err := json.Unmarshal(data, trie); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if err := trie.validate(); err != nil {
+		t.Errorf("After UnmarshalJSON Expected no error, got %s", err)
+	}
+
+	trie.count++
+	if err := trie.validate(); err == nil {
+		t.Errorf("Expected error for bad count, got nil")
+	}
+	trie.count--
+
+	trie.root.children['c'].parent = nil
+	if err := trie.validate(); err == nil {
+		t.Errorf("Expected error for bad parent, got nil")
+	}
+	trie.root.children['c'].parent = trie.root
+
+	trie.root.children['x'] = newNode(trie.root, 'x')
+	if err := trie.validate(); err == nil {
+		t.Errorf("Expected error for dead branch, got nil")
+	}
+
+}
+
+func TestTrieShortestCompletions(t *testing.T) {
+
+	list := []string{"workbench", "workflow", "works", "work", "workshop", "worker", "copy"}
+
+	cases := []struct {
+		In  string
+		K   int
+		Out []string
+	}{
+		{"work", 3, []string{"work", "works", "worker"}},
+		{"work", 10, []string{"work", "works", "worker", "workflow", "workshop", "workbench"}},
+		{"WORKS", 1, []string{"works"}},
+		{"", 2, []string{"copy", "work"}},
+		{"work", 0, []string{}},
+		{"space", 3, []string{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		got := trie.ShortestCompletions(c.In, c.K)
+		if !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieRecomputeCount(t *testing.T) {
+
+	list := []string{"cop", "copy", "copper", "copy"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	trie.count = 42
+	trie.total = 0
+
+	if got := trie.RecomputeCount(); got != 3 {
+		t.Errorf("Expected %d, got %d", 3, got)
+	}
+	if trie.Count() != 3 {
+		t.Errorf("Expected %d, got %d", 3, trie.Count())
+	}
+	if trie.Total() != 4 {
+		t.Errorf("Expected %d, got %d", 4, trie.Total())
+	}
+	if err := trie.validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+}
+
+func TestTrieMaxWordLen(t *testing.T) {
+
+	trie := New()
+
+	if got := trie.MaxWordLen(); got != 0 {
+		t.Errorf("Expected %d, got %d", 0, got)
+	}
+
+	if err := trie.Load([]string{"cop", "copper", "copperhead", "naïveté"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	cases := []struct {
+		Delete string
+		Redo   func()
+		Out    int
+	}{
+		{"", nil, 10},
+		{"", func() {
+			if err := trie.DeleteNoPrune("copperhead"); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+			trie.RecomputeCount()
+		}, 7},
+		{"naïveté", nil, 7},
+		{"", func() { trie.Compact() }, 6},
+		{"copper", nil, 6},
+		{"", func() { trie.RecomputeCount() }, 3},
+		{"cop", func() { trie.RecomputeCount() }, 0},
+	}
+
+	for _, c := range cases {
+		if c.Delete != "" {
+			if err := trie.Delete(c.Delete); err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+		}
+		if c.Redo != nil {
+			c.Redo()
+		}
+		if got := trie.MaxWordLen(); c.Out != got {
+			t.Errorf("After deleting %q Expected %d, got %d", c.Delete, c.Out, got)
+		}
+	}
+
+	var nilTrie *Trie
+	if got := nilTrie.MaxWordLen(); got != 0 {
+		t.Errorf("Expected %d, got %d", 0, got)
+	}
+
+}
+
+func TestTrieCompleteDistinct(t *testing.T) {
+
+	list := []string{"apple", "Apple", "APPLE", "apply", "Apricot", "banana"}
+
+	cases := []struct {
+		In       string
+		Limit    int
+		All      []string
+		Distinct []string
+	}{
+		{"", 0, []string{"APPLE", "Apple", "Apricot", "apple", "apply", "banana"}, []string{"APPLE", "Apricot", "apply", "banana"}},
+		{"", 2, []string{"APPLE", "Apple"}, []string{"APPLE", "Apricot"}},
+		{"app", 0, []string{"apple", "apply"}, []string{"apple", "apply"}},
+		{"cherry", 0, []string{}, []string{}},
+	}
+
+	trie := New(WithFolder(func(s string) string { return s }))
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.Complete(c.In, c.Limit); !reflect.DeepEqual(c.All, got) {
+			t.Errorf("For %q Expected %v, got %v", c.In, c.All, got)
+		}
+		if got := trie.CompleteDistinct(c.In, c.Limit); !reflect.DeepEqual(c.Distinct, got) {
+			t.Errorf("For %q distinct Expected %v, got %v", c.In, c.Distinct, got)
+		}
+	}
+
+}
+
+func TestTrieRankedComplete(t *testing.T) {
+
+	list := []string{"car", "card", "cards", "care", "carpet", "cat", "dog"}
+
+	cases := []struct {
+		In  string
+		N   int
+		Out []ScoredWord
+	}{
+		{"car", 3, []ScoredWord{{"car", 1}, {"card", 0.75}, {"care", 0.75}}},
+		{"CARD", 0, []ScoredWord{{"card", 1}, {"cards", 0.8}}},
+		{"carp", 0, []ScoredWord{{"carpet", 4.0 / 6}}},
+		{"ca", 2, []ScoredWord{{"car", 2.0 / 3}, {"cat", 2.0 / 3}}},
+		{"x", 0, []ScoredWord{}},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.RankedComplete(c.In, c.N); !reflect.DeepEqual(c.Out, got) {
+			t.Errorf("For %q Expected %v, got %v", c.In, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieSortedWords(t *testing.T) {
+
+	list := []string{"workshop", "copy", "Work", "copper", "work", "apple", ".com"}
+	want := []string{".com", "apple", "copper", "copy", "work", "workshop"}
+
+	for _, opts := range [][]Option{{}, {WithReverse()}} {
+		trie := New(opts...)
+
+		if got := trie.SortedWords(); len(got) != 0 {
+			t.Errorf("Expected no words, got %v", got)
+		}
+
+		if err := trie.Load(list); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+
+		if got := trie.SortedWords(); !reflect.DeepEqual(want, got) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+
+}
+
+func TestTrieWithCaseFolding(t *testing.T) {
+
+	cases := []struct {
+		In      string
+		Default bool
+		Folded  bool
+	}{
+		{"straße", true, true},
+		{"STRASSE", false, true},
+		{"Strasse", false, true},
+		{"STRAẞE", true, true},
+		{"strase", false, false},
+	}
+
+	def := New()
+	if err := def.Add("Straße"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	// A stand in for cases.Fold().String from golang.org/x/text/cases,
+	// covering just the full folding of the German sharp s.
+	fold := func(s string) string {
+		return strings.NewReplacer("ß", "ss", "ẞ", "ss").Replace(strings.ToLower(s))
+	}
+
+	folded := New(WithFolder(fold))
+	if err := folded.Add("Straße"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := def.Find(c.In); c.Default != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Default, got)
+		}
+		if got := folded.Find(c.In); c.Folded != got {
+			t.Errorf("For %s with case folding Expected %t, got %t", c.In, c.Folded, got)
+		}
+	}
+
+}
+
+func TestTrieWithOnChange(t *testing.T) {
+
+	type change struct {
+		Word  string
+		Added bool
+	}
+
+	changes := []change{}
+	trie := New(WithOnChange(func(word string, added bool) {
+		changes = append(changes, change{word, added})
+	}))
+
+	if err := trie.Load([]string{"copy", "Copper", "copy", "work"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Delete("copy"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := trie.Delete("copy"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %s, got %v", ErrNotFound, err)
+	}
+	if got := trie.DeleteSet(map[string]struct{}{"WORK": {}, "missing": {}}); got != 1 {
+		t.Errorf("Expected %d, got %d", 1, got)
+	}
+
+	want := []change{
+		{"copy", true},
+		{"copper", true},
+		{"work", true},
+		{"copy", false},
+		{"work", false},
+	}
+	if !reflect.DeepEqual(want, changes) {
+		t.Errorf("Expected %v, got %v", want, changes)
+	}
+
+}
+
+func TestTrieWithSeparator(t *testing.T) {
+
+	list := []string{"service.api.v1", "service.api.v2", "service.apix", "service", "web.ui"}
+
+	cases := []struct {
+		In       string
+		Prefix   bool
+		Complete []string
+	}{
+		{"service.api", true, []string{"service.api.v1", "service.api.v2"}},
+		{"service.api.", true, []string{"service.api.v1", "service.api.v2"}},
+		{"service.ap", false, []string{}},
+		{"service", true, []string{"service", "service.api.v1", "service.api.v2", "service.apix"}},
+		{"SERVICE.APIX", true, []string{"service.apix"}},
+		{"serv", false, []string{}},
+		{"web", true, []string{"web.ui"}},
+		{"web.u", false, []string{}},
+		{"", true, []string{"service", "service.api.v1", "service.api.v2", "service.apix", "web.ui"}},
+	}
+
+	trie := New(WithSeparator('.'))
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.HasPrefix(c.In); c.Prefix != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Prefix, got)
+		}
+		if got := trie.Complete(c.In, 0); !reflect.DeepEqual(c.Complete, got) {
+			t.Errorf("For %s Expected %v, got %v", c.In, c.Complete, got)
+		}
+	}
+
+	plain := New()
+	if err := plain.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if !plain.HasPrefix("service.ap") {
+		t.Errorf("Expected rune prefixes without a separator")
+	}
+
+	pruned := New(WithSeparator('.'))
+	if err := pruned.Load([]string{"a.b.c", "a.bx"}); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if err := pruned.DeleteNoPrune("a.b.c"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if pruned.HasPrefix("a.b") {
+		t.Errorf("For %s Expected %t, got %t", "a.b", false, true)
+	}
+	if got := pruned.Complete("a.b", 0); len(got) != 0 {
+		t.Errorf("For %s Expected %v, got %v", "a.b", []string{}, got)
+	}
+
+}
+
+func TestTrieWithRuneEquivalence(t *testing.T) {
+
+	list := []string{"copper", "spam", "idiot"}
+
+	leet := map[rune]rune{'0': 'o', '1': 'i', '3': 'e', '4': 'a', '$': 's', '@': 'a'}
+
+	cases := []struct {
+		In     string
+		Report string
+		Out    bool
+	}{
+		{"c0pp3r", "copper", true},
+		{"you 1d10t!", "idiot", true},
+		{"$P@M here", "spam", true},
+		{"$p4m", "spam", true},
+		{"copper", "copper", true},
+		{"c0pp", "", false},
+		{"sp@", "", false},
+	}
+
+	trie := New(WithRuneEquivalence(leet))
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	// changing the map afterwards has no effect
+	leet['x'] = 'o'
+
+	for _, c := range cases {
+		got, gotw := trie.IsContained(c.In, 0)
+		if c.Out != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Out, got)
+		}
+		if c.Report != gotw {
+			t.Errorf("For %q Expected %q, got %q", c.In, c.Report, gotw)
+		}
+	}
+
+	if !trie.Find("C0PPER") || !trie.FindInto("1d10t", nil) || trie.Find("cxpper") {
+		t.Errorf("Expected Find to use the equivalences")
+	}
+
+	if err := trie.Add("l33t"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if got := trie.SortedWords(); !reflect.DeepEqual([]string{"copper", "idiot", "leet", "spam"}, got) {
+		t.Errorf("Expected words in mapped form, got %v", got)
+	}
+
+}
+
+func TestTrieWithValidateUTF8(t *testing.T) {
+
+	cases := []struct {
+		In  string
+		Err error
+	}{
+		{"copper", nil},
+		{"café", nil},
+		{"caf\xe9", ErrInvalidUTF8},
+		{"\xff\xfe", ErrInvalidUTF8},
+		{"cop\xc3", ErrInvalidUTF8},
+	}
+
+	trie := New(WithValidateUTF8())
+
+	for _, c := range cases {
+		if err := trie.Add(c.In); !errors.Is(err, c.Err) {
+			t.Errorf("For %q Expected %v, got %v", c.In, c.Err, err)
+		}
+	}
+
+	if trie.Count() != 2 || trie.Total() != 2 {
+		t.Errorf("Expected %d words, got %d", 2, trie.Count())
+	}
+
+	if trie.Find("caf\xe9") || trie.Find("caf\uFFFD") {
+		t.Errorf("Expected invalid word not to be added")
+	}
+
+	def := New()
+	if err := def.Add("caf\xe9"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if !def.Find("caf\xff") {
+		t.Errorf("Expected invalid bytes to match as utf8.RuneError")
+	}
+
+}
+
+func TestTrieWithTrimSpace(t *testing.T) {
+
+	cases := []struct {
+		In      string
+		Default bool
+		Trimmed bool
+	}{
+		{"copper", false, true},
+		{" copper ", true, true},
+		{"\tCopper\n", false, true},
+		{"cop per", false, false},
+	}
+
+	def := New()
+	if err := def.Add(" copper "); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	trimmed := New(WithTrimSpace())
+	if err := trimmed.Add(" copper "); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := def.Find(c.In); c.Default != got {
+			t.Errorf("For %q Expected %t, got %t", c.In, c.Default, got)
+		}
+		if got := trimmed.Find(c.In); c.Trimmed != got {
+			t.Errorf("For %q trimmed Expected %t, got %t", c.In, c.Trimmed, got)
+		}
+	}
+
+	if err := trimmed.Delete("copper  "); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if !trimmed.IsEmpty() {
+		t.Errorf("Expected trie to be empty")
+	}
+
+}
+
+func TestTrieDeleteSet(t *testing.T) {
+
+	list := []string{"cop", "copy", "copper", "copperhead", "work", "workshop", "copy"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	set := map[string]struct{}{
+		"COPY":       {},
+		"copperhead": {},
+		"workshop":   {},
+		"space":      {},
+		"co":         {},
+	}
+
+	if got := trie.DeleteSet(set); got != 3 {
+		t.Errorf("Expected %d, got %d", 3, got)
+	}
+
+	want := []string{"cop", "copper", "work"}
+	if got := trie.Words(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if trie.Count() != len(want) {
+		t.Errorf("Expected %d, got %d", len(want), trie.Count())
+	}
+
+	if err := trie.validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if got := trie.DeleteSet(map[string]struct{}{}); got != 0 {
+		t.Errorf("Expected %d, got %d", 0, got)
+	}
+
+}
+
+func TestTrieDeleteNoPrune(t *testing.T) {
+
+	list := []string{"cop", "copy", "copper", "copperhead", "work", "workshop"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	nodes := trie.NodeCount()
+
+	for _, w := range []string{"copper", "copperhead", "workshop"} {
+		if err := trie.DeleteNoPrune(w); err != nil {
+			t.Errorf("Expected no error, got %s", err)
+		}
+	}
+	if err := trie.DeleteNoPrune("copperhead"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %s, got %v", ErrNotFound, err)
+	}
+
+	cases := []struct {
+		In     string
+		Find   bool
+		Prefix bool
+	}{
+		{"cop", true, true},
+		{"copper", false, false},
+		{"coppe", false, false},
+		{"copperhead", false, false},
+		{"works", false, false},
+		{"work", true, true},
+	}
+
+	for _, c := range cases {
+		if got := trie.Find(c.In); c.Find != got {
+			t.Errorf("For %s Expected %t, got %t", c.In, c.Find, got)
+		}
+		if got := trie.HasPrefix(c.In); c.Prefix != got {
+			t.Errorf("For %s prefix Expected %t, got %t", c.In, c.Prefix, got)
+		}
+	}
+
+	if trie.Count() != 3 {
+		t.Errorf("Expected %d, got %d", 3, trie.Count())
+	}
+	if trie.NodeCount() != nodes {
+		t.Errorf("Expected %d nodes before Compact, got %d", nodes, trie.NodeCount())
+	}
+
+	trie.Compact()
+
+	if trie.NodeCount() != 8 {
+		t.Errorf("Expected %d nodes after Compact, got %d", 8, trie.NodeCount())
+	}
+	if err := trie.validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+}
+
+func TestTrieRetainSet(t *testing.T) {
+
+	list := []string{"cop", "copy", "copper", "copperhead", "work", "workshop", "copy"}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	set := map[string]struct{}{
+		"COPY":       {},
+		"copperhead": {},
+		"workshop":   {},
+		"space":      {},
+		"co":         {},
+	}
+
+	if got := trie.RetainSet(set); got != 3 {
+		t.Errorf("Expected %d, got %d", 3, got)
+	}
+
+	want := []string{"copperhead", "copy", "workshop"}
+	if got := trie.SortedWords(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if trie.Count() != len(want) || trie.Total() != 4 {
+		t.Errorf("Expected %d words and %d occurrences, got %d and %d", len(want), 4, trie.Count(), trie.Total())
+	}
+
+	if err := trie.validate(); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	if got := trie.RetainSet(map[string]struct{}{}); got != 3 {
+		t.Errorf("Expected %d, got %d", 3, got)
+	}
+
+	if !trie.IsEmpty() || trie.NodeCount() != 0 {
+		t.Errorf("Expected trie to be empty, got %d nodes", trie.NodeCount())
+	}
+
+}
+
+func TestTrieDivergencePoint(t *testing.T) {
+
+	list := []string{"copy", "copper", "workflow", "workshop"}
+
+	cases := []struct {
+		A   string
+		B   string
+		Out int
+	}{
+		{"copy", "copper", 3},
+		{"workflow", "WORKSHOP", 4},
+		{"workflow", "workflow", 8},
+		{"coax", "coat", 2},
+		{"space", "spade", 0},
+		{"copper", "", 0},
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	for _, c := range cases {
+		if got := trie.DivergencePoint(c.A, c.B); c.Out != got {
+			t.Errorf("For %s and %s Expected %d, got %d", c.A, c.B, c.Out, got)
+		}
+	}
+
+}
+
+func TestTrieNil(t *testing.T) {
+
+	var trie *Trie
+
+	if trie.Find("copy") {
+		t.Errorf("Expected nil trie to find nothing")
+	}
+	if trie.Contains("copy") {
+		t.Errorf("Expected nil trie to contain nothing")
+	}
+	if trie.HasPrefix("") {
+		t.Errorf("Expected nil trie to have no prefixes")
+	}
+	if trie.HasSuffix("copy") {
+		t.Errorf("Expected nil trie to have no suffixes")
+	}
+	if got, _ := trie.IsContained("copy", 0); got {
+		t.Errorf("Expected nil trie to contain nothing")
+	}
+	if trie.Count() != 0 || trie.Total() != 0 || trie.Occurrences("copy") != 0 {
+		t.Errorf("Expected nil trie to count nothing")
+	}
+	if !trie.IsEmpty() {
+		t.Errorf("Expected nil trie to be empty")
+	}
+	if got := trie.Words(); len(got) != 0 {
+		t.Errorf("Expected nil trie to have no words, got %v", got)
+	}
+
+	lookups := map[string]int{
+		"SortedWords":         len(trie.SortedWords()),
+		"FindAll":             len(trie.FindAll("copy", 0, false)),
+		"Correct":             len(trie.Correct("copy", 1, 1)),
+		"Glob":                len(trie.Glob("c*")),
+		"NextRunes":           len(trie.NextRunes("c")),
+		"WordsInLengthRange":  len(trie.WordsInLengthRange(0, 9)),
+		"ShortestCompletions": len(trie.ShortestCompletions("c", 9)),
+		"RuneFrequency":       len(trie.RuneFrequency()),
+		"TotalRunes":          trie.TotalRunes(),
+		"DivergencePoint":     trie.DivergencePoint("copy", "cope"),
+		"CommonPrefix":        len(trie.CommonPrefix()),
+		"Intersection":        len(trie.Intersection(New())),
+		"Intersection nil":    len(New().Intersection(trie)),
+		"Encode":              len(trie.Encode()) - len(New().Encode()),
+	}
+	for name, got := range lookups {
+		if got != 0 {
+			t.Errorf("For %s Expected nil trie to find nothing, got %d", name, got)
+		}
+	}
+
+	added, removed := trie.Diff(New())
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Expected nil trie to differ by nothing, got %v %v", added, removed)
+	}
+	other := New()
+	if err := other.Add("copy"); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+	if added, removed := other.Diff(trie); !reflect.DeepEqual([]string{"copy"}, added) || len(removed) != 0 {
+		t.Errorf("Expected %v, got %v %v", []string{"copy"}, added, removed)
+	}
+
+	if got, _ := trie.IsContainedWholeWord("copy", 0); got {
+		t.Errorf("Expected nil trie to contain nothing")
+	}
+	if got, _ := trie.IsContainedMinBytes("copy", 0); got {
+		t.Errorf("Expected nil trie to contain nothing")
+	}
+	if _, got := trie.ShortestPrefix("copy"); got {
+		t.Errorf("Expected nil trie to have no prefixes")
+	}
+	if _, got := trie.Navigate("c"); got {
+		t.Errorf("Expected nil trie to have no subtrees")
+	}
+	for range trie.Matches("copy", 0) {
+		t.Errorf("Expected nil trie to match nothing")
+	}
+	if got := trie.ReplaceFunc("copy", strings.ToUpper); got != "copy" {
+		t.Errorf("Expected %s, got %s", "copy", got)
+	}
+	trie.WalkPrefix("copy", func(string, bool) {
+		t.Errorf("Expected nil trie to walk nothing")
+	})
+	if cursor := trie.Cursor(); cursor.Advance('c') || cursor.Terminated() {
+		t.Errorf("Expected nil trie cursor to go nowhere")
+	}
+
+	if _, err := trie.AddStream(strings.NewReader("copy")); err != ErrNilTrie {
+		t.Errorf("Expected %v, got %v", ErrNilTrie, err)
+	}
+	if err := trie.Add("copy"); err != ErrNilTrie {
+		t.Errorf("Expected %v, got %v", ErrNilTrie, err)
+	}
+	if err := trie.Load([]string{"copy"}); err != ErrNilTrie {
+		t.Errorf("Expected %v, got %v", ErrNilTrie, err)
+	}
+	if err := trie.Delete("copy"); err != ErrNilTrie {
+		t.Errorf("Expected %v, got %v", ErrNilTrie, err)
+	}
+	if got := trie.DeleteSet(map[string]struct{}{"copy": {}}); got != 0 {
+		t.Errorf("Expected %d, got %d", 0, got)
+	}
+
+	trie.Compact()
+	trie.RecomputeCount()
+
+}
+
+func TestTrieWalkDepth(t *testing.T) {
+
+	list := []string{"cop", "copy", "café", "work"}
+
+	type entry struct {
+		Word  string
+		Depth int
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	got := []entry{}
+	trie.WalkDepth(func(word string, depth int) bool {
+		got = append(got, entry{word, depth})
+		return true
+	})
+
+	want := []entry{{"café", 4}, {"cop", 3}, {"copy", 4}, {"work", 4}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	got = []entry{}
+	trie.WalkDepth(func(word string, depth int) bool {
+		got = append(got, entry{word, depth})
+		return len(got) < 2
+	})
+
+	if !reflect.DeepEqual(want[:2], got) {
+		t.Errorf("Expected %v, got %v", want[:2], got)
+	}
+
+}
+
+func TestTrieWalkNodes(t *testing.T) {
+
+	list := []string{"cop", "copy", "ca"}
+
+	type entry struct {
+		Prefix     string
+		Terminal   bool
+		ChildCount int
+	}
+
+	trie := New()
+
+	if err := trie.Load(list); err != nil {
+		t.Errorf("Expected no error, got %s", err)
+	}
+
+	got := []entry{}
+	trie.WalkNodes(func(prefix string, terminal bool, childCount int) bool {
+		got = append(got, entry{prefix, terminal, childCount})
+		return true
+	})
+
+	want := []entry{{"c", false, 2}, {"ca", true, 0}, {"co", false, 1}, {"cop", true, 1}, {"copy", true, 0}}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	got = []entry{}
+	trie.WalkNodes(func(prefix string, terminal bool, childCount int) bool {
+		got = append(got, entry{prefix, terminal, childCount})
+		return prefix != "co"
+	})
+
+	if !reflect.DeepEqual(want[:3], got) {
+		t.Errorf("Expected %v, got %v", want[:3], got)
+	}
+
+}
+
+func BenchmarkSearch(b *testing.B) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.full.json"); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	dictloop := 0
+
+	// run the Fib function b.N times
+	for n := 0; n < b.N; n++ {
+		if dictloop >= len(data) {
+			dictloop = 0
+		}
+		trie.Find(data[dictloop])
+		dictloop++
+	}
+}
+
+func BenchmarkSearchInto(b *testing.B) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.full.json"); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	dictloop := 0
+	buf := make([]rune, 0, 64)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if dictloop >= len(data) {
+			dictloop = 0
+		}
+		trie.FindInto(data[dictloop], buf)
+		dictloop++
+	}
+}
+
+func BenchmarkFindLoop(b *testing.B) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.full.json"); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	batch := data[:1000]
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		results := make([]bool, len(batch))
+		for i, w := range batch {
+			results[i] = trie.Find(w)
+		}
+	}
+}
+
+func BenchmarkFindBatch(b *testing.B) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.full.json"); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	batch := data[:1000]
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		trie.FindBatch(batch)
+	}
+}
+
+func BenchmarkIsExtensionOfLoop(b *testing.B) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.full.json"); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	batch := make([]string, 1000)
+	for i, w := range data[:1000] {
+		batch[i] = w + "ing"
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		results := make([]bool, len(batch))
+		for i, w := range batch {
+			results[i], _ = trie.IsExtensionOf(w)
+		}
+	}
+}
+
+func BenchmarkAnyPrefixMatches(b *testing.B) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.full.json"); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	batch := make([]string, 1000)
+	for i, w := range data[:1000] {
+		batch[i] = w + "ing"
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		trie.AnyPrefixMatches(batch)
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := New().Load(data); err != nil {
+			b.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+func BenchmarkLoadSorted(b *testing.B) {
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := New().LoadSorted(data); err != nil {
+			b.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+func BenchmarkContains(b *testing.B) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.full.json"); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	dictloop := 0
+
+	// run the Fib function b.N times
 	for n := 0; n < b.N; n++ {
 		if dictloop >= len(data) {
 			dictloop = 0
@@ -338,3 +3786,51 @@ func BenchmarkContains(b *testing.B) {
 		dictloop++
 	}
 }
+
+func BenchmarkContainsSparse(b *testing.B) {
+	trie := New()
+
+	if err := trie.Load([]string{"xylophone", "zeppelin", "quagmire", "jukebox"}); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	// Few positions in ordinary text start with one of the words, which is
+	// the common case for a short list of forbidden words.
+	input := strings.Join(data[1000:1100], " ")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		trie.IsContained(input, 0)
+	}
+}
+
+func BenchmarkContainsNoMatch(b *testing.B) {
+	trie := New()
+
+	if err := trie.LoadFile("dict.full.json"); err != nil {
+		b.Errorf("Expected no error, got %v", err)
+	}
+
+	data, err := fileToStringSlice("dict.full.json")
+
+	if err != nil {
+		b.Fatalf("Error in reading in file for testing %v", err)
+	}
+
+	// A min longer than any word means every position in the input is walked
+	// as far as the trie allows without ever reporting a match.
+	input := strings.Join(data[:100], "")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		trie.IsContained(input, 1000)
+	}
+}